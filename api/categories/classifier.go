@@ -0,0 +1,172 @@
+package categories
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Category keys of the DSM part taxonomy, stored as the type_name of a part
+const (
+	Engine     = "engine"
+	Turbo      = "turbo"
+	Drivetrain = "drivetrain"
+	Suspension = "suspension"
+	Brakes     = "brakes"
+	Body       = "body"
+	Interior   = "interior"
+	Electrical = "electrical"
+	Lighting   = "lighting"
+	Exhaust    = "exhaust"
+	Wheels     = "wheels"
+)
+
+// Category is a part category with the lower-case keywords, in German, Dutch and
+// English, that put a listing in it
+type Category struct {
+	Key      string   `json:"key"`
+	Label    string   `json:"label"`
+	Keywords []string `json:"-"`
+}
+
+// Taxonomy lists every category. Keywords shorter than four letters only match
+// whole words; longer ones also match inside compound words like "Turbolader".
+var Taxonomy = []Category{
+	{Key: Turbo, Label: "Turbo / boost", Keywords: []string{
+		"turbo", "lader", "intercooler", "ladeluft", "wastegate", "blow off", "blowoff", "bov",
+		"boost", "druckdose", "td04", "td05", "16g", "14b",
+	}},
+	{Key: Engine, Label: "Engine", Keywords: []string{
+		"engine", "motor", "4g63", "cylinder head", "zylinderkopf", "cilinderkop", "head gasket",
+		"koppakking", "camshaft", "nockenwelle", "nokkenas", "crankshaft", "kurbelwelle", "krukas",
+		"piston", "kolben", "zuiger", "oil pump", "ölpumpe", "oliepomp", "oil pan", "ölwanne",
+		"carterpan", "timing belt", "zahnriemen", "distributieriem", "valve cover", "ventildeckel",
+		"kleppendeksel", "injector", "einspritzdüse", "verstuiver", "fuel pump", "kraftstoffpumpe",
+		"benzinepomp", "radiator", "kühler", "water pump", "wasserpumpe", "waterpomp",
+		"throttle body", "drosselklappe", "gasklephuis", "intake manifold", "ansaugbrücke",
+		"inlaatspruitstuk",
+	}},
+	{Key: Exhaust, Label: "Exhaust", Keywords: []string{
+		"exhaust", "auspuff", "uitlaat", "muffler", "schalldämpfer", "einddemper", "endtopf",
+		"downpipe", "test pipe", "testpipe", "catalytic", "katalysator", "kat", "catback",
+		"cat-back", "krümmer", "abgaskrümmer", "header",
+	}},
+	{Key: Drivetrain, Label: "Drivetrain", Keywords: []string{
+		"gearbox", "transmission", "getriebe", "schaltgetriebe", "versnellingsbak", "clutch",
+		"kupplung", "koppeling", "flywheel", "schwungrad", "vliegwiel", "driveshaft", "drive shaft",
+		"antriebswelle", "gelenkwelle", "kardanwelle", "aandrijfas", "cardanas", "differential",
+		"differenzial", "differentieel", "transfer case", "verteilergetriebe", "tussenbak",
+		"shifter", "schalthebel", "cv joint", "antriebsgelenk", "homokineet",
+	}},
+	{Key: Suspension, Label: "Suspension", Keywords: []string{
+		"suspension", "fahrwerk", "ophanging", "shock", "stoßdämpfer", "stossdämpfer", "dämpfer",
+		"schokdemper", "spring", "feder", "veer", "coilover", "gewindefahrwerk", "strut",
+		"federbein", "control arm", "querlenker", "draagarm", "sway bar", "stabilisator",
+		"tie rod", "spurstange", "stuurkogel", "ball joint", "traggelenk", "fuseekogel", "bushing",
+		"steering rack", "lenkgetriebe", "stuurhuis",
+	}},
+	{Key: Brakes, Label: "Brakes", Keywords: []string{
+		"brake", "bremse", "brems", "remschijf", "remklauw", "remblok", "remmen", "caliper",
+		"bremssattel", "rotor", "bremsscheibe", "bremsbelag", "abs", "master cylinder",
+		"hauptbremszylinder", "hoofdremcilinder", "handbrake", "handbremse", "handrem",
+	}},
+	{Key: Lighting, Label: "Lighting", Keywords: []string{
+		"headlight", "scheinwerfer", "koplamp", "taillight", "tail light", "rückleuchte",
+		"heckleuchte", "achterlicht", "fog light", "nebelscheinwerfer", "mistlamp", "turn signal",
+		"blinker", "knipperlicht", "indicator", "lamp", "leuchte", "licht", "light", "bulb",
+	}},
+	{Key: Electrical, Label: "Electrical", Keywords: []string{
+		"ecu", "steuergerät", "wiring", "kabelbaum", "harness", "kabelboom", "alternator",
+		"lichtmaschine", "dynamo", "starter", "anlasser", "startmotor", "sensor", "maf",
+		"luftmassenmesser", "battery", "batterie", "accu", "relay", "relais", "switch", "schalter",
+		"schakelaar", "fuse", "sicherung", "zekering", "ignition", "zündung", "zündspule",
+		"bobine", "fensterheber", "raammotor", "window motor", "radio", "speaker", "lautsprecher",
+		"luidspreker", "klimakompressor", "ac compressor",
+	}},
+	{Key: Body, Label: "Body", Keywords: []string{
+		"bumper", "stoßstange", "stossstange", "hood", "motorhaube", "motorkap", "fender",
+		"kotflügel", "spatbord", "door", "tür", "fahrertür", "beifahrertür", "deur", "spoiler",
+		"heckflügel", "trunk", "kofferraumdeckel", "heckklappe", "achterklep", "kofferklep",
+		"hatch", "quarter panel", "seitenwand", "side skirt", "seitenschweller", "mirror",
+		"spiegel", "grille", "grill", "kühlergrill", "windshield", "windschutzscheibe", "voorruit",
+		"window", "scheibe", "roof", "dach", "dak", "body kit", "bodykit", "karosserie",
+		"carrosserie",
+	}},
+	{Key: Interior, Label: "Interior", Keywords: []string{
+		"interior", "innenraum", "interieur", "seat", "sitz", "stoel", "dashboard",
+		"armaturenbrett", "steering wheel", "lenkrad", "stuur", "carpet", "teppich", "tapijt",
+		"door panel", "türverkleidung", "verkleidung", "bekleding", "console", "mittelkonsole",
+		"gauge", "tacho", "speedometer", "kombiinstrument", "shift knob", "schaltknauf",
+		"pookknop", "headliner", "himmel", "hemel", "seat belt", "gurt", "gordel",
+	}},
+	{Key: Wheels, Label: "Wheels", Keywords: []string{
+		"wheel", "rad", "felge", "alufelge", "velg", "rim", "tire", "tyre", "reifen", "banden",
+		"hubcap", "radkappe", "wieldop", "lug nut", "radmutter", "wielmoer", "spare wheel",
+		"reserverad",
+	}},
+}
+
+// Valid reports whether key is the key of a category in the taxonomy
+func Valid(key string) bool {
+	for _, category := range Taxonomy {
+		if category.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// titleWeight makes keywords in the title count more than in the description
+const titleWeight = 3
+
+// Classify returns the category of a listing from its title and description, or ""
+// when no keyword matches. Every matched keyword scores its length, so specific
+// compounds like "motorhaube" (body) outweigh the words they contain, like "motor"
+// (engine). Ties go to the category listed first in the taxonomy.
+func Classify(title, description string) string {
+	title = strings.ToLower(title)
+	description = strings.ToLower(description)
+
+	best, bestScore := "", 0
+	for _, category := range Taxonomy {
+		score := 0
+		for _, keyword := range category.Keywords {
+			if containsKeyword(title, keyword) {
+				score += titleWeight * len(keyword)
+			}
+			if containsKeyword(description, keyword) {
+				score += len(keyword)
+			}
+		}
+		if score > bestScore {
+			best, bestScore = category.Key, score
+		}
+	}
+	return best
+}
+
+// containsKeyword reports whether text contains the keyword, as a whole word when
+// the keyword is shorter than four letters
+func containsKeyword(text, keyword string) bool {
+	if utf8.RuneCountInString(keyword) >= 4 {
+		return strings.Contains(text, keyword)
+	}
+	for start := 0; ; {
+		index := strings.Index(text[start:], keyword)
+		if index < 0 {
+			return false
+		}
+		index += start
+		end := index + len(keyword)
+		before, _ := utf8.DecodeLastRuneInString(text[:index])
+		after, _ := utf8.DecodeRuneInString(text[end:])
+		if (index == 0 || !isWordRune(before)) && (end == len(text) || !isWordRune(after)) {
+			return true
+		}
+		start = index + 1
+	}
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,124 @@
+package currency
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Rates maps ISO currency codes to the number of units of that currency per unit
+// of a common base currency. Any base works, as long as all rates share it.
+type Rates map[string]float64
+
+// ratesFile is the JSON form with an explicit base, as published by most exchange rate services
+type ratesFile struct {
+	Base  string             `json:"base"`
+	Rates map[string]float64 `json:"rates"`
+}
+
+// ParseJSON parses exchange rates from either {"base": "EUR", "rates": {"USD": 1.08}}
+// or a flat {"EUR": 1, "USD": 1.08} object. The base currency gets rate 1.
+func ParseJSON(data []byte) (Rates, error) {
+	var file ratesFile
+	if err := json.Unmarshal(data, &file); err == nil && file.Rates != nil {
+		rates := Rates(file.Rates)
+		if file.Base != "" {
+			rates[file.Base] = 1
+		}
+		return rates.normalise()
+	}
+
+	var rates Rates
+	if err := json.Unmarshal(data, &rates); err != nil {
+		return nil, fmt.Errorf("invalid exchange rates JSON: %w", err)
+	}
+	return rates.normalise()
+}
+
+// ParseCSV parses exchange rates from "currency,rate" lines. A header line is skipped.
+func ParseCSV(data []byte) (Rates, error) {
+	// Spreadsheets often save CSV with a byte order mark
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = 2
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+
+	rates := make(Rates)
+	for first := true; ; first = false {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid exchange rates CSV: %w", err)
+		}
+		rate, err := strconv.ParseFloat(strings.TrimSpace(record[1]), 64)
+		if err != nil {
+			if first {
+				continue
+			}
+			line, _ := reader.FieldPos(1)
+			return nil, fmt.Errorf("invalid rate '%s' on line %d", record[1], line)
+		}
+		rates[record[0]] = rate
+	}
+	return rates.normalise()
+}
+
+// LoadFile loads exchange rates from a .json or .csv file
+func LoadFile(path string) (Rates, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read exchange rates file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return ParseJSON(data)
+	case ".csv":
+		return ParseCSV(data)
+	default:
+		return nil, fmt.Errorf("unsupported exchange rates file '%s', expected .json or .csv", path)
+	}
+}
+
+// normalise upper-cases the currency codes and checks every code and rate
+func (r Rates) normalise() (Rates, error) {
+	if len(r) == 0 {
+		return nil, fmt.Errorf("no exchange rates given")
+	}
+
+	rates := make(Rates, len(r))
+	for code, rate := range r {
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if !ValidCode(code) {
+			return nil, fmt.Errorf("invalid currency code '%s'", code)
+		}
+		if !(rate > 0) || math.IsInf(rate, 0) {
+			return nil, fmt.Errorf("invalid rate %v for %s, must be positive", rate, code)
+		}
+		rates[code] = rate
+	}
+	return rates, nil
+}
+
+// ValidCode reports whether code looks like an ISO 4217 currency code, like "EUR"
+func ValidCode(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+	for i := 0; i < len(code); i++ {
+		if code[i] < 'A' || code[i] > 'Z' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,84 @@
+package currency
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    Rates
+		wantErr string
+	}{
+		{"plain", "EUR,1\nUSD,1.08\n", Rates{"EUR": 1, "USD": 1.08}, ""},
+		{"header", "currency,rate\nEUR,1\nUSD,1.08\n", Rates{"EUR": 1, "USD": 1.08}, ""},
+		{"quoted header", "\"Currency\", \"Rate\"\nGBP,0.85\n", Rates{"GBP": 0.85}, ""},
+		{"byte order mark", "\ufeffEUR,1\nUSD,1.08\n", Rates{"EUR": 1, "USD": 1.08}, ""},
+		{"lower case and spaces", "eur, 1\n usd , 1.08\n", Rates{"EUR": 1, "USD": 1.08}, ""},
+		{"comments", "# rates of 2025-11-01\nEUR,1\n# dollar\nUSD,1.08\n", Rates{"EUR": 1, "USD": 1.08}, ""},
+		{"header only", "currency,rate\n", nil, "no exchange rates"},
+		{"empty", "", nil, "no exchange rates"},
+		{"invalid rate after header", "currency,rate\nEUR,1\nUSD,abc\n", nil, "invalid rate 'abc' on line 3"},
+		{"line number after comment", "EUR,1\n# dollar\nUSD,abc\n", nil, "on line 3"},
+		{"zero rate", "EUR,1\nUSD,0\n", nil, "must be positive"},
+		{"negative rate", "EUR,1\nUSD,-1.08\n", nil, "must be positive"},
+		{"NaN rate", "EUR,1\nUSD,NaN\n", nil, "must be positive"},
+		{"infinite rate", "EUR,1\nUSD,Inf\n", nil, "must be positive"},
+		{"invalid code", "EUR,1\nDOLLAR,1.08\n", nil, "invalid currency code 'DOLLAR'"},
+		{"too many fields", "EUR,1,extra\n", nil, "invalid exchange rates CSV"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCSV([]byte(tt.data))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseCSV() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseCSV() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseCSV() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    Rates
+		wantErr string
+	}{
+		{"with base", `{"base": "EUR", "rates": {"USD": 1.08}}`, Rates{"EUR": 1, "USD": 1.08}, ""},
+		{"without base", `{"rates": {"EUR": 1, "USD": 1.08}}`, Rates{"EUR": 1, "USD": 1.08}, ""},
+		{"flat", `{"eur": 1, "usd": 1.08}`, Rates{"EUR": 1, "USD": 1.08}, ""},
+		{"zero rate", `{"base": "EUR", "rates": {"USD": 0}}`, nil, "must be positive"},
+		{"negative rate", `{"EUR": 1, "USD": -1.08}`, nil, "must be positive"},
+		{"empty", `{}`, nil, "no exchange rates"},
+		{"not JSON", `EUR,1`, nil, "invalid exchange rates JSON"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseJSON([]byte(tt.data))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseJSON() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseJSON() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseJSON() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
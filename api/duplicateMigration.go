@@ -0,0 +1,13 @@
+package main
+
+import (
+	"github.com/pressly/goose/v3"
+)
+
+// newDuplicateMigration returns the Go migration that used to compute the perceptual
+// hashes of the images stored before duplicates were detected. Hashing every image
+// at startup took too long on large databases, so DetectDuplicates now hashes them
+// in the background; the version stays registered for databases that applied it.
+func newDuplicateMigration() *goose.Migration {
+	return goose.NewGoMigration(20251102090100, nil, nil)
+}
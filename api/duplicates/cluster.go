@@ -0,0 +1,214 @@
+package duplicates
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"unicode"
+
+	"dsmpartsfinder-api/images"
+)
+
+// Listing is what duplicate detection compares of a part
+type Listing struct {
+	ID        int
+	SiteID    int
+	Title     string
+	Price     *int64  // in cents, in the display currency; nil when unknown
+	ImageHash *uint64 // perceptual hash of the first image; nil when there is none
+}
+
+// Weights of the signals in the similarity of two listings. Signals that are
+// missing on either listing are left out and the others weigh proportionally more.
+const (
+	titleWeight = 0.5
+	priceWeight = 0.2
+	imageWeight = 0.3
+)
+
+const (
+	// minSimilarity is the similarity from which two listings are duplicates
+	minSimilarity = 0.75
+
+	// minTitleSimilarity keeps listings with the same photo and price but clearly
+	// different titles apart, like a seller's stock photo used for several parts
+	minTitleSimilarity = 0.4
+
+	// maxPriceDifference is the relative price difference at which prices stop
+	// counting as similar, as sites round and include fees differently
+	maxPriceDifference = 0.25
+
+	// maxImageDistance is the perceptual hash distance at which photos stop
+	// counting as similar; copies of the same photo are a few bits apart
+	maxImageDistance = 16
+
+	// maxTokenListings skips title words shared by more listings than this when
+	// looking for candidate pairs, as words like "mitsubishi" say nothing
+	maxTokenListings = 50
+)
+
+// Cluster groups listings of different sites that are the same part and returns the
+// cluster of every listing that has duplicates, as the lowest ID in its cluster.
+// Listings are compared by the words in their titles, their prices and the
+// perceptual hashes of their images; only listings sharing an uncommon title word
+// are compared at all. A cluster has at most one listing per site.
+func Cluster(listings []Listing) map[int]int {
+	tokens := make([]map[string]bool, len(listings))
+	postings := make(map[string][]int)
+	for i, listing := range listings {
+		tokens[i] = titleTokens(listing.Title)
+		for token := range tokens[i] {
+			postings[token] = append(postings[token], i)
+		}
+	}
+
+	// Compare listings of different sites sharing an uncommon word
+	type pair struct {
+		i, j       int
+		similarity float64
+	}
+	var pairs []pair
+	compared := make(map[[2]int]bool)
+	for _, posting := range postings {
+		if len(posting) > maxTokenListings {
+			continue
+		}
+		for a := 0; a < len(posting); a++ {
+			for b := a + 1; b < len(posting); b++ {
+				i, j := posting[a], posting[b]
+				if listings[i].SiteID == listings[j].SiteID || compared[[2]int{i, j}] {
+					continue
+				}
+				compared[[2]int{i, j}] = true
+				if score := similarity(listings[i], listings[j], tokens[i], tokens[j]); score >= minSimilarity {
+					pairs = append(pairs, pair{i, j, score})
+				}
+			}
+		}
+	}
+
+	// Join the most similar pairs first. A cluster holds at most one listing per
+	// site, so two listings of one site are never joined through a third listing.
+	slices.SortFunc(pairs, func(a, b pair) int {
+		if c := cmp.Compare(b.similarity, a.similarity); c != 0 {
+			return c
+		}
+		return cmp.Or(cmp.Compare(listings[a.i].ID, listings[b.i].ID), cmp.Compare(listings[a.j].ID, listings[b.j].ID))
+	})
+
+	parent := make([]int, len(listings))
+	sites := make([]map[int]bool, len(listings))
+	for i, listing := range listings {
+		parent[i] = i
+		sites[i] = map[int]bool{listing.SiteID: true}
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	for _, pair := range pairs {
+		rootI, rootJ := find(pair.i), find(pair.j)
+		if rootI == rootJ || sharesSite(sites[rootI], sites[rootJ]) {
+			continue
+		}
+		parent[rootI] = rootJ
+		for site := range sites[rootI] {
+			sites[rootJ][site] = true
+		}
+		sites[rootI] = nil
+	}
+
+	// Name every cluster after its lowest ID, so clusters keep their ID as they grow
+	clusterIDs := make(map[int]int)
+	sizes := make(map[int]int)
+	for i, listing := range listings {
+		root := find(i)
+		sizes[root]++
+		if id, ok := clusterIDs[root]; !ok || listing.ID < id {
+			clusterIDs[root] = listing.ID
+		}
+	}
+
+	clusters := make(map[int]int)
+	for i, listing := range listings {
+		if root := find(i); sizes[root] > 1 {
+			clusters[listing.ID] = clusterIDs[root]
+		}
+	}
+	return clusters
+}
+
+// sharesSite reports whether two clusters have listings of the same site
+func sharesSite(a, b map[int]bool) bool {
+	for site := range a {
+		if b[site] {
+			return true
+		}
+	}
+	return false
+}
+
+// similarity returns how likely two listings are the same part, from 0 to 1
+func similarity(a, b Listing, tokensA, tokensB map[string]bool) float64 {
+	title := jaccard(tokensA, tokensB)
+	if title < minTitleSimilarity {
+		return 0
+	}
+
+	score, weight := titleWeight*title, titleWeight
+	if a.Price != nil && b.Price != nil {
+		score += priceWeight * priceSimilarity(*a.Price, *b.Price)
+		weight += priceWeight
+	}
+	if a.ImageHash != nil && b.ImageHash != nil {
+		score += imageWeight * imageSimilarity(*a.ImageHash, *b.ImageHash)
+		weight += imageWeight
+	}
+	return score / weight
+}
+
+// priceSimilarity is 1 for equal prices, falling to 0 at maxPriceDifference
+func priceSimilarity(a, b int64) float64 {
+	if a == b {
+		return 1
+	}
+	difference := float64(max(a, b)-min(a, b)) / float64(max(a, b))
+	return max(0, 1-difference/maxPriceDifference)
+}
+
+// imageSimilarity is 1 for the same photo, falling to 0 at maxImageDistance
+func imageSimilarity(a, b uint64) float64 {
+	return max(0, 1-float64(images.HashDistance(a, b))/maxImageDistance)
+}
+
+// jaccard returns the share of words two titles have in common
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	shared := 0
+	for token := range a {
+		if b[token] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+// titleTokens returns the lower-case words of a title, leaving out single letters
+func titleTokens(title string) map[string]bool {
+	tokens := make(map[string]bool)
+	words := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		if len([]rune(word)) > 1 {
+			tokens[word] = true
+		}
+	}
+	return tokens
+}
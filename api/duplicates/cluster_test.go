@@ -0,0 +1,109 @@
+package duplicates
+
+import (
+	"maps"
+	"testing"
+)
+
+func price(cents int64) *int64 { return &cents }
+
+func hash(h uint64) *uint64 { return &h }
+
+// bits returns a hash with the lowest n bits set, n bits away from hash(0)
+func bits(n int) *uint64 { return hash(1<<n - 1) }
+
+func TestImageSimilarity(t *testing.T) {
+	tests := []struct {
+		distance int
+		want     float64
+	}{
+		{0, 1},
+		{4, 0.75},
+		{8, 0.5},
+		{16, 0},
+		{40, 0},
+	}
+	for _, tt := range tests {
+		if got := imageSimilarity(0, *bits(tt.distance)); got != tt.want {
+			t.Errorf("imageSimilarity at distance %d = %v, want %v", tt.distance, got, tt.want)
+		}
+	}
+}
+
+func TestPriceSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b int64
+		want float64
+	}{
+		{10000, 10000, 1},
+		{10000, 9500, 0.8},
+		{9500, 10000, 0.8},
+		{10000, 7500, 0},
+		{10000, 100, 0},
+		{0, 10000, 0},
+	}
+	for _, tt := range tests {
+		if got := priceSimilarity(tt.a, tt.b); got < tt.want-1e-9 || got > tt.want+1e-9 {
+			t.Errorf("priceSimilarity(%d, %d) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestCluster(t *testing.T) {
+	const title = "Turbolader TD05 16G Eclipse"
+	tests := []struct {
+		name     string
+		listings []Listing
+		want     map[int]int
+	}{
+		{"same title on two sites", []Listing{
+			{ID: 1, SiteID: 1, Title: title},
+			{ID: 2, SiteID: 2, Title: title},
+		}, map[int]int{1: 1, 2: 1}},
+		{"same title on one site", []Listing{
+			{ID: 1, SiteID: 1, Title: title},
+			{ID: 2, SiteID: 1, Title: title},
+		}, map[int]int{}},
+		{"different titles", []Listing{
+			{ID: 1, SiteID: 1, Title: "Turbolader TD05 16G Eclipse"},
+			{ID: 2, SiteID: 2, Title: "Lichtmaschine Eclipse D30"},
+		}, map[int]int{}},
+		{"same photo and price, different titles", []Listing{
+			{ID: 1, SiteID: 1, Title: "Eclipse Turbolader TD05", Price: price(10000), ImageHash: hash(0)},
+			{ID: 2, SiteID: 2, Title: "Eclipse Bremssattel vorne links", Price: price(10000), ImageHash: hash(0)},
+		}, map[int]int{}},
+		{"prices too far apart", []Listing{
+			{ID: 1, SiteID: 1, Title: title, Price: price(10000)},
+			{ID: 2, SiteID: 2, Title: title, Price: price(5000)},
+		}, map[int]int{}},
+		{"image distance 13 is a copy", []Listing{
+			{ID: 1, SiteID: 1, Title: title, Price: price(10000), ImageHash: hash(0)},
+			{ID: 2, SiteID: 2, Title: title, Price: price(10000), ImageHash: bits(13)},
+		}, map[int]int{1: 1, 2: 1}},
+		{"image distance 14 is another photo", []Listing{
+			{ID: 1, SiteID: 1, Title: title, Price: price(10000), ImageHash: hash(0)},
+			{ID: 2, SiteID: 2, Title: title, Price: price(10000), ImageHash: bits(14)},
+		}, map[int]int{}},
+		{"missing image is left out", []Listing{
+			{ID: 1, SiteID: 1, Title: title, Price: price(10000), ImageHash: hash(0)},
+			{ID: 2, SiteID: 2, Title: title, Price: price(10000)},
+		}, map[int]int{1: 1, 2: 1}},
+		{"three sites in one cluster named after the lowest ID", []Listing{
+			{ID: 7, SiteID: 1, Title: title},
+			{ID: 3, SiteID: 2, Title: title},
+			{ID: 5, SiteID: 3, Title: title},
+		}, map[int]int{7: 3, 3: 3, 5: 3}},
+		{"two listings of one site are not joined through a third", []Listing{
+			{ID: 1, SiteID: 1, Title: title, Price: price(10000), ImageHash: hash(0)},
+			{ID: 2, SiteID: 1, Title: title, Price: price(10000), ImageHash: bits(8)},
+			{ID: 3, SiteID: 2, Title: title, Price: price(10000), ImageHash: bits(1)},
+		}, map[int]int{1: 1, 3: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Cluster(tt.listings); !maps.Equal(got, tt.want) {
+				t.Errorf("Cluster() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package events
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"dsmpartsfinder-api/models"
+)
+
+// Event types
+const (
+	PartCreated = "part_created" // a new listing was stored
+	PartUpdated = "part_updated" // a stored listing was edited on its site
+	PartRemoved = "part_removed" // a listing is gone from its site
+)
+
+const (
+	// historySize is the number of recent events kept for clients resuming with
+	// the ID of the last event they received
+	historySize = 1000
+
+	// subscriberBuffer is the number of events a subscriber can fall behind before
+	// it is dropped; it can resume from the history after reconnecting
+	subscriberBuffer = 256
+)
+
+// Event is a change to the stored parts
+type Event struct {
+	ID     int64        `json:"id"`
+	Type   string       `json:"type"`
+	Time   time.Time    `json:"time"`
+	PartID int          `json:"part_id"`
+	SiteID int          `json:"site_id"`
+	Part   *models.Part `json:"part,omitempty"` // the part after the change, nil when removed
+}
+
+// Filter selects the events a subscriber receives
+type Filter struct {
+	SiteIDs []int
+	Search  string // every word must occur in the name, description or part numbers
+}
+
+// Matches reports whether an event passes the filter. Removed events carry no part,
+// so they are only filtered by site.
+func (f Filter) Matches(event Event) bool {
+	if len(f.SiteIDs) > 0 && !slices.Contains(f.SiteIDs, event.SiteID) {
+		return false
+	}
+	if event.Part == nil {
+		return true
+	}
+
+	text := strings.ToLower(event.Part.Name + " " + event.Part.Description + " " + strings.Join(event.Part.PartNumbers, " "))
+	for _, word := range strings.Fields(strings.ToLower(f.Search)) {
+		if !strings.Contains(text, word) {
+			return false
+		}
+	}
+	return true
+}
+
+// Subscription receives the events published after it was created
+type Subscription struct {
+	// Events is closed when the subscriber fell too far behind or was closed
+	Events <-chan Event
+
+	bus    *Bus
+	events chan Event
+}
+
+// Close stops the subscription
+func (s *Subscription) Close() {
+	s.bus.unsubscribe(s)
+}
+
+// Bus publishes part events to subscribers in the same process, keeping a history
+// of recent events. A nil Bus drops all events.
+type Bus struct {
+	mu          sync.Mutex
+	lastID      int64
+	history     []Event
+	subscribers map[*Subscription]bool
+}
+
+// NewBus creates an event bus
+func NewBus() *Bus {
+	return &Bus{
+		history:     make([]Event, 0, historySize),
+		subscribers: make(map[*Subscription]bool),
+	}
+}
+
+// Publish assigns the next IDs to events and sends them to all subscribers
+func (b *Bus) Publish(events ...Event) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	for _, event := range events {
+		b.lastID++
+		event.ID = b.lastID
+		if event.Time.IsZero() {
+			event.Time = now
+		}
+
+		if len(b.history) == historySize {
+			copy(b.history, b.history[1:])
+			b.history = b.history[:historySize-1]
+		}
+		b.history = append(b.history, event)
+
+		for sub := range b.subscribers {
+			select {
+			case sub.events <- event:
+			default:
+				// Never block publishing on a slow client
+				delete(b.subscribers, sub)
+				close(sub.events)
+			}
+		}
+	}
+}
+
+// Subscribe subscribes to new events. Unless lastID is 0, the events in the history
+// after lastID are returned as well; a lastID above the last published one is from
+// before a restart and gets the whole history.
+func (b *Bus) Subscribe(lastID int64) (*Subscription, []Event) {
+	events := make(chan Event, subscriberBuffer)
+	sub := &Subscription{Events: events, bus: b, events: events}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	missed := make([]Event, 0)
+	if lastID != 0 {
+		if lastID > b.lastID {
+			lastID = 0
+		}
+		for _, event := range b.history {
+			if event.ID > lastID {
+				missed = append(missed, event)
+			}
+		}
+	}
+
+	b.subscribers[sub] = true
+	return sub, missed
+}
+
+// unsubscribe removes a subscription and closes its channel, unless publishing
+// already dropped it
+func (b *Bus) unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.subscribers[sub] {
+		delete(b.subscribers, sub)
+		close(sub.events)
+	}
+}
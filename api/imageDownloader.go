@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"dsmpartsfinder-api/images"
+	. "dsmpartsfinder-api/models"
+	"dsmpartsfinder-api/siteclients"
+)
+
+const (
+	// imageQueueSize bounds the number of queued downloads; images that do not fit
+	// stay pending in the database and are queued again by the next sweep
+	imageQueueSize = 1000
+
+	// maxImageAttempts is the number of sweeps an image is tried before giving up
+	maxImageAttempts = 3
+
+	// imageDownloadTimeout bounds a single download including the transport's retries
+	imageDownloadTimeout = 2 * time.Minute
+
+	// maxImageSize protects the store against absurdly large files
+	maxImageSize = 20 << 20
+)
+
+// ImageDownloader downloads part images in the background with a bounded pool of
+// workers, so storing fetched parts never waits for their images. Transient errors
+// are retried by the shared HTTP transport; images that still fail are retried by
+// later sweeps until they failed maxImageAttempts times.
+type ImageDownloader struct {
+	sqlClient  *SQLClient
+	imageStore *images.Store
+	httpClient *http.Client
+	workers    int
+	jobs       chan PendingImage
+
+	mu     sync.Mutex
+	queued map[int]bool
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewImageDownloader creates an ImageDownloader with the given number of workers
+func NewImageDownloader(sqlClient *SQLClient, imageStore *images.Store, workers int) *ImageDownloader {
+	if workers < 1 {
+		workers = 1
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &ImageDownloader{
+		sqlClient:  sqlClient,
+		imageStore: imageStore,
+		httpClient: siteclients.CreateHTTPClient(),
+		workers:    workers,
+		jobs:       make(chan PendingImage, imageQueueSize),
+		queued:     make(map[int]bool),
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+}
+
+// Start starts the workers and queues the images left pending by earlier runs
+func (d *ImageDownloader) Start() {
+	log.Printf("[ImageDownloader] Starting %d workers", d.workers)
+	for i := 0; i < d.workers; i++ {
+		d.wg.Add(1)
+		go d.worker()
+	}
+
+	if err := d.EnqueuePending(); err != nil {
+		log.Printf("[ImageDownloader] WARNING: Failed to queue pending images: %v", err)
+	}
+}
+
+// Stop cancels running downloads and waits for the workers to exit
+func (d *ImageDownloader) Stop() {
+	log.Println("[ImageDownloader] Stopping workers...")
+	d.cancel()
+	d.wg.Wait()
+	log.Println("[ImageDownloader] Workers stopped")
+}
+
+// Enqueue queues images for download and returns how many were queued. Images
+// that are already queued are skipped, and images that do not fit in the queue
+// are left for the next sweep.
+func (d *ImageDownloader) Enqueue(pending ...PendingImage) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	queued := 0
+	for _, image := range pending {
+		if image.URL == "" || d.queued[image.PartID] {
+			continue
+		}
+		select {
+		case d.jobs <- image:
+			d.queued[image.PartID] = true
+			queued++
+		default:
+			return queued
+		}
+	}
+	return queued
+}
+
+// EnqueuePending queues the images in the database that have not been downloaded yet
+func (d *ImageDownloader) EnqueuePending() error {
+	pending, err := d.sqlClient.GetPendingImages(imageQueueSize, maxImageAttempts)
+	if err != nil {
+		return fmt.Errorf("failed to get pending images: %w", err)
+	}
+
+	queued := d.Enqueue(pending...)
+	if queued > 0 {
+		log.Printf("[ImageDownloader] Queued %d pending images", queued)
+	}
+	return nil
+}
+
+// worker downloads queued images until the downloader is stopped
+func (d *ImageDownloader) worker() {
+	defer d.wg.Done()
+
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case image := <-d.jobs:
+			if err := d.download(image); err != nil && d.ctx.Err() == nil {
+				log.Printf("[ImageDownloader] WARNING: Failed to download image for part %d: %v", image.PartID, err)
+				d.sqlClient.RecordImageFailure(image.PartID)
+				if image.RunID != 0 {
+					d.sqlClient.RecordFetchRunImageFailure(image.RunID)
+				}
+			}
+
+			d.mu.Lock()
+			delete(d.queued, image.PartID)
+			d.mu.Unlock()
+		}
+	}
+}
+
+// download fetches an image, saves it in the image store and references it from its part
+func (d *ImageDownloader) download(image PendingImage) error {
+	ctx, cancel := context.WithTimeout(d.ctx, imageDownloadTimeout)
+	defer cancel()
+
+	imageURL := image.URL
+	if strings.HasPrefix(imageURL, "//") {
+		imageURL = "https:" + imageURL
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", imageURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create image request: %w", err)
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (X11; Linux x86_64; rv:143.0) Gecko/20100101 Firefox/143.0")
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch image: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code for image: %d", resp.StatusCode)
+	}
+
+	// Error pages are often served with 200, so only images are stored
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if !strings.HasPrefix(mediaType, "image/") {
+		return fmt.Errorf("unexpected content type for image: %q", resp.Header.Get("Content-Type"))
+	}
+
+	// One byte more than allowed tells a too large image from one of exactly the limit
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImageSize+1))
+	if err != nil {
+		return fmt.Errorf("failed to read image data: %w", err)
+	}
+	if len(data) > maxImageSize {
+		return fmt.Errorf("image larger than %d bytes", maxImageSize)
+	}
+	if _, err := images.Validate(data); err != nil {
+		return err
+	}
+
+	hash, err := d.imageStore.Save(data)
+	if err != nil {
+		return fmt.Errorf("failed to store image: %w", err)
+	}
+
+	// Photos in formats without a decoder are left out of duplicate detection
+	var perceptualHash *uint64
+	if phash, err := images.PerceptualHash(data); err == nil {
+		perceptualHash = &phash
+	}
+
+	return d.sqlClient.SetPartImage(image.PartID, hash, perceptualHash)
+}
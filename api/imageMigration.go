@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"log"
+
+	"dsmpartsfinder-api/images"
+
+	"github.com/pressly/goose/v3"
+)
+
+// imageMigrationBatchSize is the number of parts whose images are moved per query,
+// so large databases are not loaded into memory at once
+const imageMigrationBatchSize = 200
+
+// newImageMigration returns the Go migration that moves parts.image_base64 into the
+// image store. It runs outside a goose transaction so the database can be vacuumed
+// afterwards; the data move itself runs in its own transaction.
+func newImageMigration(store *images.Store) *goose.Migration {
+	return goose.NewGoMigration(20251023090100,
+		&goose.GoFunc{RunDB: func(ctx context.Context, db *sql.DB) error {
+			return migrateImagesToStore(ctx, db, store)
+		}},
+		&goose.GoFunc{RunDB: func(ctx context.Context, db *sql.DB) error {
+			return migrateImagesToBase64(ctx, db, store)
+		}},
+	)
+}
+
+// migrateImagesToStore writes every base64 image to the store, references it in
+// part_images and drops the image_base64 column
+func migrateImagesToStore(ctx context.Context, db *sql.DB, store *images.Store) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	moved, skipped, lastID := 0, 0, 0
+	for {
+		rows, err := tx.QueryContext(ctx, `
+			SELECT id, image_base64 FROM parts
+			WHERE id > ? AND image_base64 IS NOT NULL AND image_base64 != ''
+			ORDER BY id
+			LIMIT ?
+		`, lastID, imageMigrationBatchSize)
+		if err != nil {
+			return fmt.Errorf("failed to query part images: %w", err)
+		}
+
+		type partImage struct {
+			id   int
+			data string
+		}
+		batch := make([]partImage, 0, imageMigrationBatchSize)
+		for rows.Next() {
+			var image partImage
+			if err := rows.Scan(&image.id, &image.data); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan part image: %w", err)
+			}
+			batch = append(batch, image)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to read part images: %w", err)
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		for _, image := range batch {
+			lastID = image.id
+
+			data, err := base64.StdEncoding.DecodeString(image.data)
+			if err != nil {
+				log.Printf("[ImageMigration] WARNING: Dropping undecodable image of part %d: %v", image.id, err)
+				skipped++
+				continue
+			}
+
+			hash, err := store.Save(data)
+			if err != nil {
+				return fmt.Errorf("failed to store image of part %d: %w", image.id, err)
+			}
+
+			if _, err := tx.ExecContext(ctx, `
+				INSERT OR IGNORE INTO part_images (part_id, position, image_hash) VALUES (?, 0, ?)
+			`, image.id, hash); err != nil {
+				return fmt.Errorf("failed to reference image of part %d: %w", image.id, err)
+			}
+			moved++
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE parts DROP COLUMN image_base64`); err != nil {
+		return fmt.Errorf("failed to drop image_base64 column: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	log.Printf("[ImageMigration] Moved %d images to the image store (%d skipped)", moved, skipped)
+
+	// Give the space of the base64 data back to the file system
+	if _, err := db.ExecContext(ctx, `VACUUM`); err != nil {
+		log.Printf("[ImageMigration] WARNING: Failed to vacuum database: %v", err)
+	}
+	return nil
+}
+
+// migrateImagesToBase64 restores the image_base64 column from the image store
+func migrateImagesToBase64(ctx context.Context, db *sql.DB, store *images.Store) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE parts ADD COLUMN image_base64 TEXT`); err != nil {
+		return fmt.Errorf("failed to add image_base64 column: %w", err)
+	}
+
+	rows, err := tx.QueryContext(ctx, `SELECT part_id, image_hash FROM part_images WHERE position = 0`)
+	if err != nil {
+		return fmt.Errorf("failed to query part images: %w", err)
+	}
+	references := make(map[int]string)
+	for rows.Next() {
+		var partID int
+		var hash string
+		if err := rows.Scan(&partID, &hash); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan part image: %w", err)
+		}
+		references[partID] = hash
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read part images: %w", err)
+	}
+
+	for partID, hash := range references {
+		data, err := store.Read(hash)
+		if err != nil {
+			log.Printf("[ImageMigration] WARNING: Image %s of part %d is missing from the store: %v", hash, partID, err)
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE parts SET image_base64 = ? WHERE id = ?`,
+			base64.StdEncoding.EncodeToString(data), partID); err != nil {
+			return fmt.Errorf("failed to restore image of part %d: %w", partID, err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM part_images`); err != nil {
+		return fmt.Errorf("failed to clear part images: %w", err)
+	}
+
+	return tx.Commit()
+}
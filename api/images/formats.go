@@ -0,0 +1,57 @@
+package images
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"net/http"
+	"slices"
+)
+
+// contentTypes are the image formats accepted from sites and served by the API.
+// SVG is left out on purpose, as it can carry scripts.
+var contentTypes = []string{"image/jpeg", "image/png", "image/gif", "image/webp", "image/bmp"}
+
+// decodedTypes are the formats with a registered decoder, see thumbnails.go
+var decodedTypes = []string{"image/jpeg", "image/png", "image/gif"}
+
+// maxPixels caps the dimensions of images that get decoded. A small file can
+// declare a huge canvas, and decoding allocates 4 bytes per pixel up front.
+const maxPixels = 40_000_000
+
+// checkPixels returns an error when an image's declared dimensions exceed maxPixels
+func checkPixels(config image.Config) error {
+	if int64(config.Width)*int64(config.Height) > maxPixels {
+		return fmt.Errorf("image of %dx%d pixels exceeds the limit of %d pixels", config.Width, config.Height, maxPixels)
+	}
+	return nil
+}
+
+// ContentType returns the media type of an image from its leading bytes, or an error
+// when they are not an image in one of the accepted formats
+func ContentType(data []byte) (string, error) {
+	contentType := http.DetectContentType(data)
+	if !slices.Contains(contentTypes, contentType) {
+		return "", fmt.Errorf("not an image: detected %s", contentType)
+	}
+	return contentType, nil
+}
+
+// Validate checks that data is an image in one of the accepted formats whose header
+// decodes within the pixel limit, for the formats with a decoder, and returns its media type
+func Validate(data []byte) (string, error) {
+	contentType, err := ContentType(data)
+	if err != nil {
+		return "", err
+	}
+	if slices.Contains(decodedTypes, contentType) {
+		config, _, err := image.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			return "", fmt.Errorf("failed to decode %s image: %w", contentType, err)
+		}
+		if err := checkPixels(config); err != nil {
+			return "", err
+		}
+	}
+	return contentType, nil
+}
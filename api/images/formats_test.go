@@ -0,0 +1,90 @@
+package images
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+// pngHeader returns the signature and IHDR chunk of a PNG declaring width x height
+// pixels, enough for DecodeConfig but without any pixel data
+func pngHeader(width, height int) []byte {
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:], uint32(width))
+	binary.BigEndian.PutUint32(ihdr[4:], uint32(height))
+	ihdr[8] = 8 // bit depth
+	ihdr[9] = 6 // RGBA
+
+	var buf bytes.Buffer
+	buf.WriteString("\x89PNG\r\n\x1a\n")
+	binary.Write(&buf, binary.BigEndian, uint32(len(ihdr)))
+	chunk := append([]byte("IHDR"), ihdr...)
+	buf.Write(chunk)
+	binary.Write(&buf, binary.BigEndian, crc32.ChecksumIEEE(chunk))
+	return buf.Bytes()
+}
+
+func encodePNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		want    string
+		wantErr string
+	}{
+		{"png", encodePNG(t, 4, 4), "image/png", ""},
+		{"html", []byte("<html><body>not found</body></html>"), "", "not an image"},
+		{"broken png", []byte("\x89PNG\r\n\x1a\ngarbage"), "", "failed to decode"},
+		{"pixel cap", pngHeader(8000, 8000), "", "exceeds the limit"},
+		{"at pixel cap", pngHeader(8000, 5000), "image/png", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Validate(tt.data)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Validate() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Validate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestThumbnailRejectsPixelCap(t *testing.T) {
+	if _, err := thumbnail(pngHeader(20000, 20000), SizeSmall); err == nil || !strings.Contains(err.Error(), "exceeds the limit") {
+		t.Fatalf("thumbnail() error = %v, want pixel limit error", err)
+	}
+}
+
+func TestThumbnailScalesDown(t *testing.T) {
+	data, err := thumbnail(encodePNG(t, 640, 320), SizeSmall)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Width != SizeSmall || config.Height != SizeSmall/2 {
+		t.Errorf("thumbnail is %dx%d, want %dx%d", config.Width, config.Height, SizeSmall, SizeSmall/2)
+	}
+}
@@ -0,0 +1,85 @@
+package images
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math/bits"
+)
+
+// PerceptualHash returns the difference hash (dHash) of an image: the image is
+// shrunk to 9x8 grey pixels and every bit tells whether a pixel is brighter than
+// its right neighbour. Scaled, recompressed or slightly edited copies of a photo
+// get hashes only a few bits apart, see HashDistance.
+func PerceptualHash(data []byte) (uint64, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return 0, fmt.Errorf("failed to decode image: %w", err)
+	}
+	if err := checkPixels(config); err != nil {
+		return 0, err
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return 0, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	const width, height = 9, 8
+	grey := greyscale(src, width, height)
+
+	var hash uint64
+	for y := 0; y < height; y++ {
+		for x := 0; x < width-1; x++ {
+			hash <<= 1
+			if grey[y*width+x] > grey[y*width+x+1] {
+				hash |= 1
+			}
+		}
+	}
+	return hash, nil
+}
+
+// HashDistance returns the number of bits two perceptual hashes differ in, from 0
+// for the same photo to around 32 for unrelated ones
+func HashDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// greyscale scales an image to width x height grey values by averaging the source
+// pixels covered by each target pixel, ignoring the aspect ratio. Transparent areas
+// are flattened onto white like thumbnails are.
+func greyscale(src image.Image, width, height int) []int {
+	bounds := src.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+
+	flat := image.NewRGBA(image.Rect(0, 0, srcW, srcH))
+	draw.Draw(flat, flat.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), src, bounds.Min, draw.Over)
+
+	grey := make([]int, width*height)
+	for y := 0; y < height; y++ {
+		y0 := y * srcH / height
+		y1 := max(y0+1, (y+1)*srcH/height)
+
+		for x := 0; x < width; x++ {
+			x0 := x * srcW / width
+			x1 := max(x0+1, (x+1)*srcW/width)
+
+			var sum, count int
+			for sy := y0; sy < y1; sy++ {
+				offset := flat.PixOffset(x0, sy)
+				for sx := x0; sx < x1; sx++ {
+					// ITU-R 601 luma, scaled by 1000
+					sum += 299*int(flat.Pix[offset]) + 587*int(flat.Pix[offset+1]) + 114*int(flat.Pix[offset+2])
+					offset += 4
+					count++
+				}
+			}
+			grey[y*width+x] = sum / count
+		}
+	}
+	return grey
+}
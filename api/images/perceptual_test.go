@@ -0,0 +1,12 @@
+package images
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPerceptualHashRejectsPixelCap(t *testing.T) {
+	if _, err := PerceptualHash(pngHeader(20000, 20000)); err == nil || !strings.Contains(err.Error(), "exceeds the limit") {
+		t.Fatalf("PerceptualHash() error = %v, want pixel limit error", err)
+	}
+}
@@ -0,0 +1,213 @@
+package images
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Store is a content-addressed image store on disk. Every image is stored once
+// under the SHA-256 hash of its content, so identical images from different
+// listings share one file.
+type Store struct {
+	dir string
+}
+
+// NewStore creates a Store in the given directory, creating it if needed
+func NewStore(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create image directory: %w", err)
+	}
+	return &Store{dir: dir}, nil
+}
+
+// Hash returns the content hash an image is stored under
+func Hash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// ValidHash reports whether a string is a well-formed image hash
+func ValidHash(hash string) bool {
+	if len(hash) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(hash)
+	return err == nil
+}
+
+// Path returns the file path of an image; images are spread over subdirectories
+// named after the first two characters of their hash
+func (s *Store) Path(hash string) (string, error) {
+	if !ValidHash(hash) {
+		return "", fmt.Errorf("invalid image hash '%s'", hash)
+	}
+	return filepath.Join(s.dir, hash[:2], hash), nil
+}
+
+// sizePath returns the file path of an image at a size
+func (s *Store) sizePath(hash string, size int) (string, error) {
+	path, err := s.Path(hash)
+	if err != nil || size == SizeOriginal {
+		return path, err
+	}
+	if !ValidSize(size) {
+		return "", fmt.Errorf("invalid image size %d", size)
+	}
+	return fmt.Sprintf("%s_%d", path, size), nil
+}
+
+// Save stores an image with its thumbnails and returns its hash. Saving an image
+// that is already stored only refreshes its modification time.
+func (s *Store) Save(data []byte) (string, error) {
+	if len(data) == 0 {
+		return "", fmt.Errorf("empty image")
+	}
+
+	hash := Hash(data)
+	path, err := s.Path(hash)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := os.Stat(path); err == nil {
+		now := time.Now()
+		os.Chtimes(path, now, now)
+		return hash, nil
+	}
+
+	if err := writeFile(path, data); err != nil {
+		return "", err
+	}
+
+	for _, size := range ThumbnailSizes {
+		if err := s.saveThumbnail(hash, data, size); err != nil {
+			log.Printf("[ImageStore] WARNING: Failed to create %dpx thumbnail of %s: %v", size, hash, err)
+		}
+	}
+
+	return hash, nil
+}
+
+// saveThumbnail stores the thumbnail of an image at a size. Images that cannot be
+// decoded are stored as they are, so they are not decoded again on every request.
+func (s *Store) saveThumbnail(hash string, data []byte, size int) error {
+	path, err := s.sizePath(hash, size)
+	if err != nil {
+		return err
+	}
+
+	thumbnailData, err := thumbnail(data, size)
+	if err != nil {
+		log.Printf("[ImageStore] Could not resize image %s, using the original: %v", hash, err)
+		thumbnailData = data
+	}
+	return writeFile(path, thumbnailData)
+}
+
+// writeFile writes to a temporary file first so a crash never leaves a truncated image behind
+func writeFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create image directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to create image file: %w", err)
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to create image file: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write image: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write image: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to store image: %w", err)
+	}
+	return nil
+}
+
+// Open opens a stored image for reading
+func (s *Store) Open(hash string) (*os.File, error) {
+	return s.OpenSize(hash, SizeOriginal)
+}
+
+// OpenSize opens a stored image at a size. Missing thumbnails, for example of
+// images stored before thumbnails existed, are created on first use.
+func (s *Store) OpenSize(hash string, size int) (*os.File, error) {
+	path, err := s.sizePath(hash, size)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if err == nil || size == SizeOriginal || !errors.Is(err, fs.ErrNotExist) {
+		return file, err
+	}
+
+	data, err := s.Read(hash)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.saveThumbnail(hash, data, size); err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+// Read returns the content of a stored image
+func (s *Store) Read(hash string) ([]byte, error) {
+	path, err := s.Path(hash)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(path)
+}
+
+// Prune deletes images that are not in keep and have not been saved for at least
+// minAge, so images saved by a fetch that has not stored its parts yet survive
+func (s *Store) Prune(keep map[string]bool, minAge time.Duration) (int, error) {
+	cutoff := time.Now().Add(-minAge)
+	removed := 0
+
+	err := filepath.WalkDir(s.dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		// Thumbnails are named after the hash of their original
+		hash, _, _ := strings.Cut(entry.Name(), "_")
+		if entry.IsDir() || keep[hash] {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil || info.ModTime().After(cutoff) {
+			return nil
+		}
+
+		if err := os.Remove(path); err != nil {
+			log.Printf("[ImageStore] WARNING: Failed to remove image %s: %v", path, err)
+			return nil
+		}
+		removed++
+		return nil
+	})
+
+	return removed, err
+}
@@ -0,0 +1,112 @@
+package images
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"slices"
+
+	// Decoders for the formats sites serve; formats without a decoder, like WebP,
+	// are served in their original form at every size
+	_ "image/gif"
+	_ "image/png"
+)
+
+// Image sizes, as the maximum length of the longest side in pixels.
+// SizeOriginal is the image as the site served it.
+const (
+	SizeOriginal = 0
+	SizeSmall    = 160
+	SizeLarge    = 480
+)
+
+// ThumbnailSizes are the sizes generated for every stored image
+var ThumbnailSizes = []int{SizeSmall, SizeLarge}
+
+// thumbnailQuality is the JPEG quality thumbnails are encoded with
+const thumbnailQuality = 85
+
+// ValidSize reports whether images can be requested at a size
+func ValidSize(size int) bool {
+	return size == SizeOriginal || slices.Contains(ThumbnailSizes, size)
+}
+
+// thumbnail returns the image scaled down to fit within size x size, encoded as JPEG.
+// Images that already fit are returned unchanged.
+func thumbnail(data []byte, size int) ([]byte, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	if config.Width <= size && config.Height <= size {
+		return data, nil
+	}
+	if err := checkPixels(config); err != nil {
+		return nil, err
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, resize(src, size), &jpeg.Options{Quality: thumbnailQuality}); err != nil {
+		return nil, fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// resize scales an image down to fit within size x size by averaging the source
+// pixels covered by each target pixel. Transparent areas are flattened onto white,
+// since thumbnails are stored as JPEG.
+func resize(src image.Image, size int) *image.RGBA {
+	bounds := src.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+
+	dstW, dstH := size, size
+	if srcW > srcH {
+		dstH = max(1, srcH*size/srcW)
+	} else {
+		dstW = max(1, srcW*size/srcH)
+	}
+
+	// Work on a flattened RGBA copy so pixels can be read directly
+	flat := image.NewRGBA(image.Rect(0, 0, srcW, srcH))
+	draw.Draw(flat, flat.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), src, bounds.Min, draw.Over)
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	for y := 0; y < dstH; y++ {
+		y0 := y * srcH / dstH
+		y1 := max(y0+1, (y+1)*srcH/dstH)
+
+		for x := 0; x < dstW; x++ {
+			x0 := x * srcW / dstW
+			x1 := max(x0+1, (x+1)*srcW/dstW)
+
+			var r, g, b, count int
+			for sy := y0; sy < y1; sy++ {
+				offset := flat.PixOffset(x0, sy)
+				for sx := x0; sx < x1; sx++ {
+					r += int(flat.Pix[offset])
+					g += int(flat.Pix[offset+1])
+					b += int(flat.Pix[offset+2])
+					offset += 4
+				}
+				count += x1 - x0
+			}
+
+			i := dst.PixOffset(x, y)
+			dst.Pix[i] = uint8(r / count)
+			dst.Pix[i+1] = uint8(g / count)
+			dst.Pix[i+2] = uint8(b / count)
+			dst.Pix[i+3] = 0xff
+		}
+	}
+
+	return dst
+}
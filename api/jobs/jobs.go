@@ -0,0 +1,342 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Job and site statuses
+const (
+	StatusPending   = "pending" // sites only, before their fetch started
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+	StatusCancelled = "cancelled"
+)
+
+// What started a job
+const (
+	TriggerAPI       = "api"
+	TriggerScheduler = "scheduler"
+	TriggerStartup   = "startup"
+)
+
+// maxFinishedJobs is the number of finished jobs kept for their status
+const maxFinishedJobs = 100
+
+var (
+	// ErrNotFound is returned for unknown job IDs
+	ErrNotFound = errors.New("job not found")
+
+	// ErrFinished is returned when cancelling a job that already finished
+	ErrFinished = errors.New("job already finished")
+)
+
+// SiteProgress is the progress of the fetch of one site in a job
+type SiteProgress struct {
+	SiteID       int        `json:"site_id"`
+	RunID        int        `json:"run_id,omitempty"` // the fetch run recorded for the site
+	Status       string     `json:"status"`
+	Pages        int        `json:"pages"`         // pages fetched and stored
+	PartsFound   int        `json:"parts_found"`   // listings on the fetched pages
+	PartsNew     int        `json:"parts_new"`     // listings stored as new parts
+	PartsUpdated int        `json:"parts_updated"` // stored parts that were edited on the site
+	PartsDeleted int        `json:"parts_deleted"` // stored parts the site no longer lists, now gone
+	PartsFailed  int        `json:"parts_failed"`  // listings that could not be stored
+	Errors       []string   `json:"errors"`
+	StartedAt    *time.Time `json:"started_at"`
+	FinishedAt   *time.Time `json:"finished_at"`
+}
+
+// Info is the state of a job at one moment
+type Info struct {
+	ID         int            `json:"id"`
+	Trigger    string         `json:"trigger"`
+	Status     string         `json:"status"`
+	Error      string         `json:"error,omitempty"`
+	Sites      []SiteProgress `json:"sites"`
+	CreatedAt  time.Time      `json:"created_at"`
+	FinishedAt *time.Time     `json:"finished_at"`
+}
+
+// Job is a fetch running in the background
+type Job struct {
+	mu        sync.Mutex
+	info      Info
+	cancel    context.CancelFunc
+	cancelled bool
+	done      chan struct{}
+}
+
+// Info returns a copy of the current state of the job
+func (j *Job) Info() Info {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	info := j.info
+	info.Sites = make([]SiteProgress, len(j.info.Sites))
+	for i, site := range j.info.Sites {
+		site.Errors = slices.Clone(site.Errors)
+		info.Sites[i] = site
+	}
+	return info
+}
+
+// ID returns the ID of the job
+func (j *Job) ID() int {
+	return j.info.ID
+}
+
+// Trigger returns what started the job
+func (j *Job) Trigger() string {
+	return j.info.Trigger
+}
+
+// Wait blocks until the job finished
+func (j *Job) Wait() {
+	<-j.done
+}
+
+// Site returns the tracker of the fetch of a site in the job, nil for sites that
+// are not part of it
+func (j *Job) Site(siteID int) *Site {
+	for i := range j.info.Sites {
+		if j.info.Sites[i].SiteID == siteID {
+			return &Site{job: j, index: i}
+		}
+	}
+	return nil
+}
+
+// finish sets the final status of the job from the error of its run
+func (j *Job) finish(err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := time.Now()
+	j.info.FinishedAt = &now
+	switch {
+	case j.cancelled:
+		j.info.Status = StatusCancelled
+	case err != nil:
+		j.info.Status = StatusFailed
+		j.info.Error = err.Error()
+	default:
+		j.info.Status = StatusSucceeded
+	}
+	close(j.done)
+}
+
+// Site tracks the progress of the fetch of one site. A nil Site tracks nothing, so
+// fetches outside of jobs need no checks.
+type Site struct {
+	job   *Job
+	index int
+}
+
+// update changes the progress of the site under the lock of its job
+func (s *Site) update(change func(progress *SiteProgress)) {
+	if s == nil {
+		return
+	}
+	s.job.mu.Lock()
+	defer s.job.mu.Unlock()
+	change(&s.job.info.Sites[s.index])
+}
+
+// Start marks the fetch of the site as running
+func (s *Site) Start() {
+	s.update(func(progress *SiteProgress) {
+		now := time.Now()
+		progress.Status = StatusRunning
+		progress.StartedAt = &now
+	})
+}
+
+// Progress returns a copy of the current progress of the site
+func (s *Site) Progress() SiteProgress {
+	if s == nil {
+		return SiteProgress{}
+	}
+	s.job.mu.Lock()
+	defer s.job.mu.Unlock()
+
+	progress := s.job.info.Sites[s.index]
+	progress.Errors = slices.Clone(progress.Errors)
+	return progress
+}
+
+// SetRunID links the site to the fetch run recorded for it
+func (s *Site) SetRunID(id int) {
+	s.update(func(progress *SiteProgress) {
+		progress.RunID = id
+	})
+}
+
+// RunID returns the fetch run recorded for the site, 0 when there is none
+func (s *Site) RunID() int {
+	return s.Progress().RunID
+}
+
+// AddPage records a fetched page with the number of listings on it, stored as new
+// parts, updated as edited parts and failed to store
+func (s *Site) AddPage(found, stored, updated, failed int) {
+	s.update(func(progress *SiteProgress) {
+		progress.Pages++
+		progress.PartsFound += found
+		progress.PartsNew += stored
+		progress.PartsUpdated += updated
+		progress.PartsFailed += failed
+	})
+}
+
+// AddDeleted records stored parts that went gone because the site no longer lists them
+func (s *Site) AddDeleted(deleted int) {
+	s.update(func(progress *SiteProgress) {
+		progress.PartsDeleted += deleted
+	})
+}
+
+// AddError records an error that did not end the fetch of the site
+func (s *Site) AddError(err error) {
+	s.update(func(progress *SiteProgress) {
+		progress.Errors = append(progress.Errors, err.Error())
+	})
+}
+
+// Finish marks the fetch of the site as done, failed with err when it is not nil
+func (s *Site) Finish(err error) {
+	s.update(func(progress *SiteProgress) {
+		now := time.Now()
+		progress.FinishedAt = &now
+		switch {
+		case errors.Is(err, context.Canceled):
+			progress.Status = StatusCancelled
+		case err != nil:
+			progress.Status = StatusFailed
+			progress.Errors = append(progress.Errors, err.Error())
+		default:
+			progress.Status = StatusSucceeded
+		}
+	})
+}
+
+// Manager runs jobs and keeps them in memory for their status
+type Manager struct {
+	mu     sync.Mutex
+	jobs   []*Job // oldest first
+	nextID int
+}
+
+// NewManager creates a job manager
+func NewManager() *Manager {
+	return &Manager{nextID: 1}
+}
+
+// Start runs a job fetching the given sites in the background. run gets a context
+// that is cancelled when the job is; the job failed when run returns an error.
+func (m *Manager) Start(trigger string, siteIDs []int, run func(ctx context.Context, job *Job) error) *Job {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	job := &Job{
+		info: Info{
+			Trigger:   trigger,
+			Status:    StatusRunning,
+			Sites:     make([]SiteProgress, len(siteIDs)),
+			CreatedAt: time.Now(),
+		},
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	for i, siteID := range siteIDs {
+		job.info.Sites[i] = SiteProgress{SiteID: siteID, Status: StatusPending, Errors: make([]string, 0)}
+	}
+
+	m.mu.Lock()
+	job.info.ID = m.nextID
+	m.nextID++
+	m.jobs = append(m.jobs, job)
+	m.prune()
+	m.mu.Unlock()
+
+	go func() {
+		defer cancel()
+		job.finish(run(ctx, job))
+	}()
+	return job
+}
+
+// prune forgets the oldest finished jobs beyond maxFinishedJobs
+func (m *Manager) prune() {
+	finished := 0
+	for i := len(m.jobs) - 1; i >= 0; i-- {
+		select {
+		case <-m.jobs[i].done:
+			finished++
+			if finished > maxFinishedJobs {
+				m.jobs = slices.Delete(m.jobs, i, i+1)
+			}
+		default:
+		}
+	}
+}
+
+// get returns the job with an ID
+func (m *Manager) get(id int) (*Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, job := range m.jobs {
+		if job.info.ID == id {
+			return job, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// Get returns the state of a job
+func (m *Manager) Get(id int) (Info, error) {
+	job, err := m.get(id)
+	if err != nil {
+		return Info{}, err
+	}
+	return job.Info(), nil
+}
+
+// List returns the state of all kept jobs, newest first
+func (m *Manager) List() []Info {
+	m.mu.Lock()
+	jobs := slices.Clone(m.jobs)
+	m.mu.Unlock()
+
+	infos := make([]Info, 0, len(jobs))
+	for i := len(jobs) - 1; i >= 0; i-- {
+		infos = append(infos, jobs[i].Info())
+	}
+	return infos
+}
+
+// Cancel cancels a running job through its context and returns its state. Sites
+// store the pages they fetched before the cancellation.
+func (m *Manager) Cancel(id int) (Info, error) {
+	job, err := m.get(id)
+	if err != nil {
+		return Info{}, err
+	}
+
+	job.mu.Lock()
+	running := job.info.FinishedAt == nil
+	if running {
+		job.cancelled = true
+	}
+	job.mu.Unlock()
+
+	if !running {
+		return job.Info(), ErrFinished
+	}
+	job.cancel()
+	return job.Info(), nil
+}
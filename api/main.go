@@ -9,11 +9,17 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
+	"dsmpartsfinder-api/currency"
+	"dsmpartsfinder-api/events"
+	"dsmpartsfinder-api/images"
+	"dsmpartsfinder-api/jobs"
+	"dsmpartsfinder-api/notify"
 	"dsmpartsfinder-api/routes"
-	"dsmpartsfinder-api/scrapers"
+	_ "dsmpartsfinder-api/scrapers"
 	"dsmpartsfinder-api/siteclients"
 
 	"github.com/gin-contrib/cors"
@@ -47,12 +53,21 @@ func main() {
 		log.Printf("Warning: Could not load .env file: %v", err)
 	}
 
+	// Configure rate limits and retries for outgoing requests to the sites
+	siteclients.ConfigureHTTP(siteclients.TransportConfigFromEnv())
+
 	// Get configurable database path
 	dbPath := os.Getenv("DATABASE_PATH")
 	if dbPath == "" {
 		dbPath = "./sqlite.db"
 	}
 
+	// Get configurable image store path
+	imagesPath := os.Getenv("IMAGES_PATH")
+	if imagesPath == "" {
+		imagesPath = "./images"
+	}
+
 	// Get configurable port
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -61,6 +76,7 @@ func main() {
 
 	log.Printf("Starting DSM Parts finder on port %s", port)
 	log.Printf("Opening database connection on %s", dbPath)
+	log.Printf("Storing images in %s", imagesPath)
 	log.Printf("Application is running in %s mode", gin.Mode())
 
 	// If in release mode, log to file
@@ -76,8 +92,6 @@ func main() {
 	}
 
 	r := gin.Default()
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
 
 	// Open database connection
 	sqlClient, err := NewSQLClient("./sqlite.db")
@@ -86,48 +100,121 @@ func main() {
 	}
 	defer sqlClient.Close()
 
+	// Open image store
+	imageStore, err := images.NewStore(imagesPath)
+	if err != nil {
+		log.Fatalf("Failed to open image store: %v", err)
+	}
+
 	subFS, err := fs.Sub(migrationsFS, "migrations")
 	if err != nil {
 		log.Fatalf("Failed to create sub FS: %v", err)
 	}
-	provider, err := goose.NewProvider(goose.DialectSQLite3, sqlClient.db, subFS)
+	provider, err := goose.NewProvider(goose.DialectSQLite3, sqlClient.db, subFS,
+		goose.WithGoMigrations(
+			newImageMigration(imageStore),
+			newPriceMigration(),
+			newTypeMigration(),
+			newPartNumberMigration(),
+			newDuplicateMigration(),
+		),
+	)
 	if err != nil {
 		log.Fatalf("Failed to create migration provider: %v", err)
 	}
-	_, err = provider.Up(ctx)
+	// Migrations run without a deadline: converting the data of a large database
+	// can take minutes, and a migration cut short would only fail again on restart
+	_, err = provider.Up(context.Background())
 	if err != nil {
 		log.Fatalf("Failed to run migrations: %v", err)
 	}
 
+	// Fetches cut short by a stop of the previous process never finished
+	if interrupted, err := sqlClient.FailInterruptedFetchRuns(); err != nil {
+		log.Printf("Warning: Could not mark interrupted fetch runs: %v", err)
+	} else if interrupted > 0 {
+		log.Printf("Marked %d interrupted fetch runs as failed", interrupted)
+	}
+
+	// Convert prices into the display currency, with rates from a file if configured
+	displayCurrency := strings.ToUpper(os.Getenv("DISPLAY_CURRENCY"))
+	if displayCurrency == "" {
+		displayCurrency = "EUR"
+	}
+	if !currency.ValidCode(displayCurrency) {
+		log.Fatalf("Invalid display currency '%s'", displayCurrency)
+	}
+	if err := sqlClient.SetDisplayCurrency(displayCurrency); err != nil {
+		log.Fatalf("Failed to convert prices to %s: %v", displayCurrency, err)
+	}
+	if ratesFile := os.Getenv("EXCHANGE_RATES_FILE"); ratesFile != "" {
+		rates, err := currency.LoadFile(ratesFile)
+		if err != nil {
+			log.Printf("Warning: Could not load exchange rates: %v", err)
+		} else if err := sqlClient.ReplaceExchangeRates(rates); err != nil {
+			log.Printf("Warning: Could not store exchange rates: %v", err)
+		} else {
+			log.Printf("Loaded %d exchange rates from %s", len(rates), ratesFile)
+		}
+	}
+
+	// Start downloading part images in the background
+	imageWorkers, err := strconv.Atoi(os.Getenv("IMAGE_WORKERS"))
+	if err != nil || imageWorkers < 1 {
+		imageWorkers = 4
+	}
+	imageDownloader := NewImageDownloader(sqlClient, imageStore, imageWorkers)
+	imageDownloader.Start()
+	defer imageDownloader.Stop()
+
+	// Send notifications to the destinations in a config file if configured
+	var notifier *notify.Dispatcher
+	if notifyConfig := os.Getenv("NOTIFY_CONFIG"); notifyConfig != "" {
+		notifier, err = notify.LoadFile(notifyConfig)
+		if err != nil {
+			log.Printf("Warning: Could not load notification config: %v", err)
+		} else {
+			log.Printf("Sending notifications to %d destination(s) from %s", notifier.Len(), notifyConfig)
+		}
+	}
+	defer notifier.Close()
+
+	// Stored, edited and removed parts are published to the clients of /api/events
+	eventBus := events.NewBus()
+
+	// Fetches run as background jobs, whether started by the API or the scheduler
+	jobManager := jobs.NewManager()
+
 	// Initialize PartsService
-	partsService := NewPartsService(sqlClient)
+	partsService := NewPartsService(sqlClient, imageStore, imageDownloader, notifier, eventBus, jobManager)
 
 	sites, err := sqlClient.GetAllSites()
 	if err != nil {
 		log.Fatalf("Failed to get sites from database: %v", err)
 	}
 
-	// Register site clients dynamically based on DB entries
+	// Register site clients dynamically based on the client_type of each DB entry
 	for _, site := range sites {
-		switch site.Name {
-		case "SchadeAutos":
-			client := siteclients.NewSchadeAutosClient(site.ID)
-			partsService.RegisterSiteClient(site.ID, client)
-		case "Kleinanzeigen":
-			client := scrapers.NewKleinanzeigenClient(site.ID)
-			partsService.RegisterSiteClient(site.ID, client)
-		case "Ebay":
-			clientID := os.Getenv("EBAY_CLIENT_ID")
-			clientSecret := os.Getenv("EBAY_CLIENT_SECRET")
-			client := siteclients.NewEbayClient(site.ID, clientID, clientSecret, false)
-			partsService.RegisterSiteClient(site.ID, client)
-		default:
-			log.Printf("No client implementation for site '%s' (site ID: %d), skipping registration", site.Name, site.ID)
+		if site.ClientType == "" {
+			log.Printf("No client type configured for site '%s' (site ID: %d), skipping registration", site.Name, site.ID)
+			continue
+		}
+
+		client, err := siteclients.NewClient(site.ClientType, siteclients.ClientConfig{
+			SiteID: site.ID,
+			Name:   site.Name,
+			URL:    site.URL,
+			Config: site.ClientConfig,
+		})
+		if err != nil {
+			log.Printf("Failed to create client for site '%s' (site ID: %d): %v, skipping registration", site.Name, site.ID, err)
+			continue
 		}
+		partsService.RegisterSiteClient(site.ID, client)
 	}
 
 	// Initialize and start scheduler for automatic fetching
-	scheduler := NewScheduler(partsService)
+	scheduler := NewScheduler(partsService, jobManager)
 	go func() {
 		if err := scheduler.Start(); err != nil {
 			log.Printf("Scheduler error: %v", err)
@@ -155,7 +242,7 @@ func main() {
 	}))
 
 	// Register API endpoints from routes.go
-	routes.RegisterAPIRoutes(r, sqlClient, partsService)
+	routes.RegisterAPIRoutes(r, sqlClient, partsService, imageStore, eventBus, jobManager, scheduler)
 
 	// Serve embedded frontend files
 	frontendSubFS, err := fs.Sub(frontendFS, "frontend/dist")
@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE sites ADD COLUMN client_type TEXT NOT NULL DEFAULT '';
+UPDATE sites SET client_type = 'schadeautos' WHERE site_name = 'SchadeAutos';
+UPDATE sites SET client_type = 'kleinanzeigen' WHERE site_name = 'Kleinanzeigen';
+UPDATE sites SET client_type = 'ebay' WHERE site_name = 'Ebay';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE sites DROP COLUMN client_type;
+-- +goose StatementEnd
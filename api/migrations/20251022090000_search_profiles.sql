@@ -0,0 +1,34 @@
+-- +goose Up
+CREATE TABLE search_profiles (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    vehicle_type TEXT NOT NULL DEFAULT 'P',
+    make TEXT NOT NULL,
+    base_model TEXT NOT NULL DEFAULT '',
+    model TEXT NOT NULL DEFAULT '',
+    year_from INTEGER NOT NULL DEFAULT 0,
+    year_to INTEGER NOT NULL DEFAULT 0,
+    result_limit INTEGER NOT NULL DEFAULT 0,
+    enabled BOOLEAN NOT NULL DEFAULT 1,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE part_profiles (
+    part_id INTEGER NOT NULL,
+    profile_id INTEGER NOT NULL,
+    FOREIGN KEY (part_id) REFERENCES parts(id) ON DELETE CASCADE,
+    FOREIGN KEY (profile_id) REFERENCES search_profiles(id) ON DELETE CASCADE,
+    PRIMARY KEY (part_id, profile_id)
+);
+
+CREATE INDEX idx_part_profiles_profile_id ON part_profiles(profile_id);
+
+-- The search the scheduler used to run from code
+INSERT INTO search_profiles (name, vehicle_type, make, base_model, model, year_from, year_to, result_limit, enabled)
+VALUES ('Mitsubishi Eclipse', 'P', 'Mitsubishi', 'Eclipse', '', 1989, 2000, 10000, 1);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_part_profiles_profile_id;
+DROP TABLE part_profiles;
+DROP TABLE search_profiles;
@@ -0,0 +1,18 @@
+-- +goose Up
+CREATE TABLE part_images (
+    part_id INTEGER NOT NULL,
+    position INTEGER NOT NULL DEFAULT 0,
+    image_hash TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (part_id) REFERENCES parts(id) ON DELETE CASCADE,
+    PRIMARY KEY (part_id, position)
+);
+
+CREATE INDEX idx_part_images_image_hash ON part_images(image_hash);
+
+-- The base64 data in parts.image_base64 is moved into the image store by the
+-- Go migration 20251023090100 in imageMigration.go
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_part_images_image_hash;
+DROP TABLE part_images;
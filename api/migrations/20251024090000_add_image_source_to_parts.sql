@@ -0,0 +1,9 @@
+-- +goose Up
+-- Images are downloaded in the background after a part is stored; image_source_url
+-- is where to download it from and image_attempts counts failed downloads
+ALTER TABLE parts ADD COLUMN image_source_url TEXT NOT NULL DEFAULT '';
+ALTER TABLE parts ADD COLUMN image_attempts INTEGER NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE parts DROP COLUMN image_attempts;
+ALTER TABLE parts DROP COLUMN image_source_url;
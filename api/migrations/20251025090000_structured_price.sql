@@ -0,0 +1,16 @@
+-- +goose Up
+-- price stays the text shown by the site; price_amount is in cents and NULL when
+-- the amount is unknown or on request. price_type is fixed, negotiable, free or on_request.
+ALTER TABLE parts ADD COLUMN price_amount INTEGER;
+ALTER TABLE parts ADD COLUMN price_currency TEXT NOT NULL DEFAULT '';
+ALTER TABLE parts ADD COLUMN price_type TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX idx_parts_price_amount ON parts(price_amount);
+
+-- Existing prices are parsed by the Go migration 20251025090100 in priceMigration.go
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_parts_price_amount;
+ALTER TABLE parts DROP COLUMN price_type;
+ALTER TABLE parts DROP COLUMN price_currency;
+ALTER TABLE parts DROP COLUMN price_amount;
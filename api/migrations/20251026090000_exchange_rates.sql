@@ -0,0 +1,23 @@
+-- +goose Up
+-- rate is the number of units of the currency per unit of the base currency the
+-- rates were loaded with; the base currency itself has rate 1
+CREATE TABLE exchange_rates (
+    currency TEXT PRIMARY KEY,
+    rate REAL NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO exchange_rates (currency, rate) VALUES ('EUR', 1);
+
+-- The price in the display currency, in cents, used for filtering and sorting.
+-- NULL when the amount is unknown or there is no rate for the currency.
+ALTER TABLE parts ADD COLUMN price_display_amount INTEGER;
+
+CREATE INDEX idx_parts_price_display_amount ON parts(price_display_amount);
+
+UPDATE parts SET price_display_amount = price_amount WHERE price_currency = 'EUR';
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_parts_price_display_amount;
+ALTER TABLE parts DROP COLUMN price_display_amount;
+DROP TABLE exchange_rates;
@@ -0,0 +1,23 @@
+-- +goose Up
+-- Every row is a price change of a listing that was seen again, from the old
+-- price to the new one. The current price stays in parts.
+CREATE TABLE part_price_history (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    part_id INTEGER NOT NULL,
+    old_price TEXT NOT NULL DEFAULT '',
+    old_price_amount INTEGER,
+    old_price_currency TEXT NOT NULL DEFAULT '',
+    old_price_type TEXT NOT NULL DEFAULT '',
+    new_price TEXT NOT NULL DEFAULT '',
+    new_price_amount INTEGER,
+    new_price_currency TEXT NOT NULL DEFAULT '',
+    new_price_type TEXT NOT NULL DEFAULT '',
+    changed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (part_id) REFERENCES parts(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_part_price_history_part_id ON part_price_history(part_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_part_price_history_part_id;
+DROP TABLE part_price_history;
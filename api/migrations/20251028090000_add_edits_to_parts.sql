@@ -0,0 +1,10 @@
+-- +goose Up
+-- A fetch that sees a stored listing again writes the fields that changed and
+-- records them: edited_fields is a comma separated list of the fields changed by
+-- the last edit, like 'name,price', and edited_at when that was
+ALTER TABLE parts ADD COLUMN edited_fields TEXT NOT NULL DEFAULT '';
+ALTER TABLE parts ADD COLUMN edited_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE parts DROP COLUMN edited_at;
+ALTER TABLE parts DROP COLUMN edited_fields;
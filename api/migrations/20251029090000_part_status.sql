@@ -0,0 +1,21 @@
+-- +goose Up
+-- Parts are no longer deleted when a site stops listing them. status goes from
+-- active to missing to gone as the time since last_seen passes the thresholds of
+-- their site, and back to active when they are seen again. Gone parts are purged
+-- purge_after_days after they went gone; 0 keeps them forever.
+ALTER TABLE parts ADD COLUMN status TEXT NOT NULL DEFAULT 'active';
+ALTER TABLE parts ADD COLUMN status_changed_at TIMESTAMP;
+
+CREATE INDEX idx_parts_site_id_status ON parts(site_id, status);
+
+ALTER TABLE sites ADD COLUMN missing_after_hours INTEGER NOT NULL DEFAULT 24;
+ALTER TABLE sites ADD COLUMN gone_after_hours INTEGER NOT NULL DEFAULT 72;
+ALTER TABLE sites ADD COLUMN purge_after_days INTEGER NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE sites DROP COLUMN purge_after_days;
+ALTER TABLE sites DROP COLUMN gone_after_hours;
+ALTER TABLE sites DROP COLUMN missing_after_hours;
+DROP INDEX IF EXISTS idx_parts_site_id_status;
+ALTER TABLE parts DROP COLUMN status_changed_at;
+ALTER TABLE parts DROP COLUMN status;
@@ -0,0 +1,39 @@
+-- +goose Up
+-- Full-text index over the searchable text of parts, kept in sync by triggers.
+-- remove_diacritics lets "kuhler" find "Kühler".
+CREATE VIRTUAL TABLE parts_fts USING fts5(
+    name, description, type_name,
+    content='parts', content_rowid='id',
+    tokenize='unicode61 remove_diacritics 2'
+);
+
+-- +goose StatementBegin
+CREATE TRIGGER parts_fts_insert AFTER INSERT ON parts BEGIN
+    INSERT INTO parts_fts (rowid, name, description, type_name)
+    VALUES (new.id, new.name, new.description, new.type_name);
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER parts_fts_delete AFTER DELETE ON parts BEGIN
+    INSERT INTO parts_fts (parts_fts, rowid, name, description, type_name)
+    VALUES ('delete', old.id, old.name, old.description, old.type_name);
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER parts_fts_update AFTER UPDATE OF name, description, type_name ON parts BEGIN
+    INSERT INTO parts_fts (parts_fts, rowid, name, description, type_name)
+    VALUES ('delete', old.id, old.name, old.description, old.type_name);
+    INSERT INTO parts_fts (rowid, name, description, type_name)
+    VALUES (new.id, new.name, new.description, new.type_name);
+END;
+-- +goose StatementEnd
+
+INSERT INTO parts_fts (parts_fts) VALUES ('rebuild');
+
+-- +goose Down
+DROP TRIGGER IF EXISTS parts_fts_update;
+DROP TRIGGER IF EXISTS parts_fts_delete;
+DROP TRIGGER IF EXISTS parts_fts_insert;
+DROP TABLE parts_fts;
@@ -0,0 +1,12 @@
+-- +goose Up
+-- type_name is the category of a part in the taxonomy of categories/classifier.go.
+-- It is set by the classifier unless type_overridden says it was set by hand.
+ALTER TABLE parts ADD COLUMN type_overridden BOOLEAN NOT NULL DEFAULT 0;
+
+CREATE INDEX idx_parts_type_name ON parts(type_name);
+
+-- Existing parts are classified by the Go migration 20251031090100 in typeMigration.go
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_parts_type_name;
+ALTER TABLE parts DROP COLUMN type_overridden;
@@ -0,0 +1,17 @@
+-- +goose Up
+-- OEM part numbers found in the name and description of a part, normalised by
+-- partnumbers/extract.go, e.g. MD123456 for "md-123 456"
+CREATE TABLE part_numbers (
+    part_id INTEGER NOT NULL,
+    number TEXT NOT NULL,
+    FOREIGN KEY (part_id) REFERENCES parts(id) ON DELETE CASCADE,
+    PRIMARY KEY (part_id, number)
+);
+
+CREATE INDEX idx_part_numbers_number ON part_numbers(number);
+
+-- Existing parts are indexed by the Go migration 20251101090100 in partNumberMigration.go
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_part_numbers_number;
+DROP TABLE part_numbers;
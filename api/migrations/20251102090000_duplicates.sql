@@ -0,0 +1,17 @@
+-- +goose Up
+-- Perceptual hash (dHash) of an image, stored as signed 64-bit integer, for
+-- finding copies of the same photo; NULL for images that cannot be decoded
+ALTER TABLE part_images ADD COLUMN perceptual_hash INTEGER;
+
+-- Parts on different sites that are the same listing share a cluster_id: the
+-- lowest id in the cluster. It is NULL for parts without duplicates.
+ALTER TABLE parts ADD COLUMN cluster_id INTEGER;
+
+CREATE INDEX idx_parts_cluster_id ON parts(cluster_id);
+
+-- Stored images are hashed in the background by PartsService.DetectDuplicates
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_parts_cluster_id;
+ALTER TABLE parts DROP COLUMN cluster_id;
+ALTER TABLE part_images DROP COLUMN perceptual_hash;
@@ -0,0 +1,32 @@
+-- +goose Up
+-- query holds the filter parameters of GET /api/parts as query string
+CREATE TABLE saved_searches (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    query TEXT NOT NULL DEFAULT '',
+    enabled BOOLEAN NOT NULL DEFAULT 1,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Every row is a newly stored part that matched a saved search; read_at is NULL
+-- while the alert is unread
+CREATE TABLE alerts (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    saved_search_id INTEGER NOT NULL,
+    part_id INTEGER NOT NULL,
+    read_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (saved_search_id) REFERENCES saved_searches(id) ON DELETE CASCADE,
+    FOREIGN KEY (part_id) REFERENCES parts(id) ON DELETE CASCADE,
+    UNIQUE(saved_search_id, part_id)
+);
+
+CREATE INDEX idx_alerts_part_id ON alerts(part_id);
+CREATE INDEX idx_alerts_read_at ON alerts(read_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_alerts_read_at;
+DROP INDEX IF EXISTS idx_alerts_part_id;
+DROP TABLE alerts;
+DROP TABLE saved_searches;
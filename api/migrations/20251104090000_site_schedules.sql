@@ -0,0 +1,15 @@
+-- +goose Up
+-- Every site is fetched on its own schedule: a cron expression with seconds, or
+-- empty to only fetch it on request. Each run is delayed by a random time of up to
+-- schedule_jitter_seconds, and runs starting in the local hours from
+-- quiet_hours_start up to quiet_hours_end are skipped; NULL hours have no quiet time.
+ALTER TABLE sites ADD COLUMN schedule TEXT NOT NULL DEFAULT '0 0 * * * *';
+ALTER TABLE sites ADD COLUMN schedule_jitter_seconds INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE sites ADD COLUMN quiet_hours_start INTEGER;
+ALTER TABLE sites ADD COLUMN quiet_hours_end INTEGER;
+
+-- +goose Down
+ALTER TABLE sites DROP COLUMN quiet_hours_end;
+ALTER TABLE sites DROP COLUMN quiet_hours_start;
+ALTER TABLE sites DROP COLUMN schedule_jitter_seconds;
+ALTER TABLE sites DROP COLUMN schedule;
@@ -0,0 +1,31 @@
+-- +goose Up
+-- Every row is the fetch of one site in one job, started by the scheduler, the API
+-- or at startup. status is running until the fetch succeeded, failed or was
+-- cancelled. parts_deleted counts the stored parts that went gone because the site
+-- no longer lists them; image_failures counts the images queued by the run that
+-- failed to download, which can grow after the run finished.
+CREATE TABLE fetch_runs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    site_id INTEGER NOT NULL,
+    job_id INTEGER NOT NULL,
+    trigger TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'running',
+    started_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    finished_at TIMESTAMP,
+    parts_fetched INTEGER NOT NULL DEFAULT 0,
+    parts_inserted INTEGER NOT NULL DEFAULT 0,
+    parts_updated INTEGER NOT NULL DEFAULT 0,
+    parts_deleted INTEGER NOT NULL DEFAULT 0,
+    parts_failed INTEGER NOT NULL DEFAULT 0,
+    image_failures INTEGER NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+    FOREIGN KEY (site_id) REFERENCES sites(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_fetch_runs_site_id_started_at ON fetch_runs(site_id, started_at);
+CREATE INDEX idx_fetch_runs_started_at ON fetch_runs(started_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_fetch_runs_started_at;
+DROP INDEX IF EXISTS idx_fetch_runs_site_id_started_at;
+DROP TABLE IF EXISTS fetch_runs;
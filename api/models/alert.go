@@ -0,0 +1,74 @@
+package models
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// SavedSearch is a stored parts search that newly stored parts are matched against
+type SavedSearch struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	Query     string    `json:"query"` // filter parameters of GET /api/parts, like "search=turbo&max_price=300"
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Filter returns the parts filter the saved search stands for
+func (s SavedSearch) Filter() PartsFilter {
+	query, _ := url.ParseQuery(s.Query)
+	return ParsePartsFilter(query)
+}
+
+// SavedSearchRequest represents the request body for creating or updating a saved search
+type SavedSearchRequest struct {
+	Name    string `json:"name" binding:"required"`
+	Query   string `json:"query"`
+	Enabled *bool  `json:"enabled"`
+}
+
+// ToSavedSearch converts the request into a SavedSearch, enabling it unless stated
+// otherwise. The query may start with "?" as copied from a URL.
+func (r SavedSearchRequest) ToSavedSearch() (SavedSearch, error) {
+	query := strings.TrimPrefix(strings.TrimSpace(r.Query), "?")
+	if _, err := url.ParseQuery(query); err != nil {
+		return SavedSearch{}, fmt.Errorf("invalid query: %w", err)
+	}
+
+	enabled := true
+	if r.Enabled != nil {
+		enabled = *r.Enabled
+	}
+	return SavedSearch{
+		Name:    r.Name,
+		Query:   query,
+		Enabled: enabled,
+	}, nil
+}
+
+// Alert is a newly stored part that matched a saved search
+type Alert struct {
+	ID              int        `json:"id"`
+	SavedSearchID   int        `json:"saved_search_id"`
+	SavedSearchName string     `json:"saved_search_name"`
+	PartID          int        `json:"part_id"`
+	Part            *Part      `json:"part"`
+	Read            bool       `json:"read"`
+	ReadAt          *time.Time `json:"read_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// AlertsFilter holds the filters that can be applied when listing alerts
+type AlertsFilter struct {
+	SavedSearchID int
+	UnreadOnly    bool
+	CreatedSince  time.Time // only alerts created at or after this time when set
+}
+
+// UpdateAlertRequest represents the request body for marking an alert read or unread
+type UpdateAlertRequest struct {
+	Read *bool `json:"read" binding:"required"`
+}
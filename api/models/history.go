@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// PriceChange is a change of the price of a listing between two fetches.
+// Amounts are in cents and nil when unknown or on request.
+type PriceChange struct {
+	ID               int       `json:"id"`
+	PartID           int       `json:"part_id"`
+	OldPrice         string    `json:"old_price"`
+	OldPriceAmount   *int64    `json:"old_price_amount"`
+	OldPriceCurrency string    `json:"old_price_currency"`
+	OldPriceType     string    `json:"old_price_type"`
+	NewPrice         string    `json:"new_price"`
+	NewPriceAmount   *int64    `json:"new_price_amount"`
+	NewPriceCurrency string    `json:"new_price_currency"`
+	NewPriceType     string    `json:"new_price_type"`
+	ChangedAt        time.Time `json:"changed_at"`
+}
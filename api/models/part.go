@@ -1,22 +1,143 @@
 package models
 
-import "time"
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"dsmpartsfinder-api/partnumbers"
+	"dsmpartsfinder-api/siteclients"
+)
 
 // Part represents a car part scraped from a site
 type Part struct {
-	ID           int        `json:"id"`
-	PartID       string     `json:"part_id"`
-	Description  string     `json:"description"`
-	TypeName     string     `json:"type_name"`
-	Name         string     `json:"name"`
-	ImageBase64  string     `json:"image_base64"`
-	URL          string     `json:"url"`
-	SiteID       int        `json:"site_id"`
-	Price        string     `json:"price"`
-	CreatedAt    time.Time  `json:"created_at"`
-	UpdatedAt    time.Time  `json:"updated_at"`
-	LastSeen     time.Time  `json:"last_seen"`
-	CreationDate *time.Time `json:"creation_date"`
+	ID                 int        `json:"id"`
+	PartID             string     `json:"part_id"`
+	Description        string     `json:"description"`
+	TypeName           string     `json:"type_name"`       // category in the part taxonomy, "" when unknown
+	TypeOverridden     bool       `json:"type_overridden"` // TypeName was set by hand instead of by the classifier
+	Name               string     `json:"name"`
+	ImageURL           string     `json:"image_url"`
+	ImageHash          string     `json:"-"`
+	ImageSourceURL     string     `json:"-"`
+	URL                string     `json:"url"`
+	SiteID             int        `json:"site_id"`
+	Price              string     `json:"price"`
+	PriceAmount        *int64     `json:"price_amount"`
+	PriceCurrency      string     `json:"price_currency"`
+	PriceType          string     `json:"price_type"`
+	PriceDisplayAmount *int64     `json:"price_display_amount"` // in cents, in the display currency
+	PriceDropAmount    *int64     `json:"price_drop_amount"`    // in cents below the highest earlier price, nil when it did not drop
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+	LastSeen           time.Time  `json:"last_seen"`
+	CreationDate       *time.Time `json:"creation_date"`
+	ProfileIDs         []int      `json:"profile_ids"`
+	PartNumbers        []string   `json:"part_numbers"`  // normalised OEM part numbers found in the name and description
+	ClusterID          *int       `json:"cluster_id"`    // shared by the listings of this part on other sites, nil without duplicates
+	DuplicateIDs       []int      `json:"duplicate_ids"` // the other parts in the cluster
+	EditedFields       []string   `json:"edited_fields"` // fields changed by the last edit of the listing
+	EditedAt           *time.Time `json:"edited_at"`
+	Status             string     `json:"status"`
+	StatusChangedAt    *time.Time `json:"status_changed_at"`
+}
+
+// Part statuses: parts go missing and then gone when their site stops listing them
+const (
+	PartStatusActive  = "active"
+	PartStatusMissing = "missing"
+	PartStatusGone    = "gone"
+)
+
+// Listing fields that are compared when a stored part is fetched again
+const (
+	FieldName        = "name"
+	FieldDescription = "description"
+	FieldURL         = "url"
+	FieldImage       = "image"
+	FieldPrice       = "price"
+)
+
+// PendingImage is a part image that still has to be downloaded
+type PendingImage struct {
+	PartID int
+	URL    string
+	RunID  int // the fetch run that queued the image, 0 for retries
+}
+
+// ImageURL returns the API URL an image from the image store is served at in the
+// given size, or "" for no image. A size of 0 is the original image.
+func ImageURL(hash string, size int) string {
+	if hash == "" {
+		return ""
+	}
+	if size == 0 {
+		return "/api/images/" + hash
+	}
+	return fmt.Sprintf("/api/images/%s?size=%d", hash, size)
+}
+
+// PartsFilter holds the filters that can be applied when listing parts
+type PartsFilter struct {
+	TypeFilter         string
+	SiteIDs            []int
+	NewerThan          time.Time
+	Search             string
+	ProfileID          int
+	PartNumber         string // normalised OEM part number
+	MinPrice           *int64 // in cents, in the display currency
+	MaxPrice           *int64 // in cents, in the display currency
+	PriceDropped       bool   // only parts cheaper than they were listed for before
+	IncludeGone        bool   // also parts that are gone from their site
+	CollapseDuplicates bool   // only one part of every cluster of duplicates
+}
+
+// IsEmpty reports whether no filter is set
+func (f PartsFilter) IsEmpty() bool {
+	return f.TypeFilter == "" && len(f.SiteIDs) == 0 && f.NewerThan.IsZero() && f.Search == "" && f.ProfileID == 0 && f.PartNumber == "" &&
+		f.MinPrice == nil && f.MaxPrice == nil && !f.PriceDropped && !f.IncludeGone && !f.CollapseDuplicates
+}
+
+// ParsePartsFilter reads the parts filter query parameters of GET /api/parts, which
+// saved searches store as well
+func ParsePartsFilter(query url.Values) PartsFilter {
+	filter := PartsFilter{
+		TypeFilter: query.Get("type"),
+		SiteIDs:    make([]int, 0),
+		Search:     query.Get("search"),
+	}
+
+	for _, idStr := range query["site_ids[]"] {
+		if id, err := strconv.Atoi(idStr); err == nil {
+			filter.SiteIDs = append(filter.SiteIDs, id)
+		}
+	}
+
+	if query.Get("newer_than_hours") != "" {
+		hours, _ := strconv.Atoi(query.Get("newer_than_hours"))
+		filter.NewerThan = time.Now().Add(-time.Duration(hours) * time.Hour)
+	}
+
+	filter.ProfileID, _ = strconv.Atoi(query.Get("profile_id"))
+
+	// Part numbers are matched exactly, after normalising spaces, dashes and case like stored ones
+	if number := query.Get("part_number"); number != "" {
+		filter.PartNumber = partnumbers.Normalise(number)
+	}
+
+	// Prices are given in units of the display currency, like min_price=49.95, and filtered in cents
+	if amount, err := siteclients.ParseAmount(query.Get("min_price")); err == nil {
+		filter.MinPrice = &amount
+	}
+	if amount, err := siteclients.ParseAmount(query.Get("max_price")); err == nil {
+		filter.MaxPrice = &amount
+	}
+	filter.PriceDropped = query.Get("price_dropped") == "true"
+	filter.IncludeGone = query.Get("include_gone") == "true"
+	filter.CollapseDuplicates = query.Get("collapse_duplicates") == "true"
+
+	return filter
 }
 
 // FetchPartsRequest represents the request body for fetching parts from a site
@@ -31,3 +152,9 @@ type FetchPartsRequest struct {
 	Offset      int    `json:"offset"`
 	Limit       int    `json:"limit"`
 }
+
+// SetPartTypeRequest represents the request body for setting the type of a part by
+// hand; an empty type hands the part back to the classifier
+type SetPartTypeRequest struct {
+	TypeName string `json:"type_name"`
+}
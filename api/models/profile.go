@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+// SearchProfile represents a persisted vehicle search that the scheduler runs against every site
+type SearchProfile struct {
+	ID          int       `json:"id"`
+	Name        string    `json:"name"`
+	VehicleType string    `json:"vehicle_type"`
+	Make        string    `json:"make"`
+	BaseModel   string    `json:"base_model"`
+	Model       string    `json:"model"`
+	YearFrom    int       `json:"year_from"`
+	YearTo      int       `json:"year_to"`
+	Limit       int       `json:"limit"`
+	Enabled     bool      `json:"enabled"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// SearchProfileRequest represents the request body for creating or updating a search profile
+type SearchProfileRequest struct {
+	Name        string `json:"name" binding:"required"`
+	VehicleType string `json:"vehicle_type"`
+	Make        string `json:"make" binding:"required"`
+	BaseModel   string `json:"base_model"`
+	Model       string `json:"model"`
+	YearFrom    int    `json:"year_from"`
+	YearTo      int    `json:"year_to"`
+	Limit       int    `json:"limit"`
+	Enabled     *bool  `json:"enabled"`
+}
+
+// ToProfile converts the request into a SearchProfile, enabling it unless stated otherwise
+func (r SearchProfileRequest) ToProfile() SearchProfile {
+	enabled := true
+	if r.Enabled != nil {
+		enabled = *r.Enabled
+	}
+	vehicleType := r.VehicleType
+	if vehicleType == "" {
+		vehicleType = "P"
+	}
+	return SearchProfile{
+		Name:        r.Name,
+		VehicleType: vehicleType,
+		Make:        r.Make,
+		BaseModel:   r.BaseModel,
+		Model:       r.Model,
+		YearFrom:    r.YearFrom,
+		YearTo:      r.YearTo,
+		Limit:       r.Limit,
+		Enabled:     enabled,
+	}
+}
@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// ExchangeRate is the number of units of a currency per unit of the base currency
+// the rates were loaded with
+type ExchangeRate struct {
+	Currency  string    `json:"currency"`
+	Rate      float64   `json:"rate"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// FetchRun is the fetch of one site in one job, with what it stored
+type FetchRun struct {
+	ID            int        `json:"id"`
+	SiteID        int        `json:"site_id"`
+	SiteName      string     `json:"site_name"`
+	JobID         int        `json:"job_id"`  // the job in /api/jobs, until a restart
+	Trigger       string     `json:"trigger"` // scheduler, api or startup
+	Status        string     `json:"status"`  // running, succeeded, failed or cancelled
+	StartedAt     time.Time  `json:"started_at"`
+	FinishedAt    *time.Time `json:"finished_at"`
+	PartsFetched  int        `json:"parts_fetched"`
+	PartsInserted int        `json:"parts_inserted"`
+	PartsUpdated  int        `json:"parts_updated"`
+	PartsDeleted  int        `json:"parts_deleted"`
+	PartsFailed   int        `json:"parts_failed"`
+	ImageFailures int        `json:"image_failures"`
+	Error         string     `json:"error"`
+}
+
+// FetchRunsFilter holds the filters that can be applied when listing fetch runs
+type FetchRunsFilter struct {
+	SiteID     int
+	Trigger    string
+	Status     string
+	Since      time.Time // only runs started at or after this time when set
+	Until      time.Time // only runs started before this time when set
+	MaxFetched *int      // only runs that fetched at most this many parts when set
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// ScheduleParser parses the cron expressions of site schedules, which start with
+// a seconds field like "0 0 * * * *"; descriptors like "@every 2h" work as well
+var ScheduleParser = cron.NewParser(
+	cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor,
+)
+
+// SiteSchedule is when the scheduler fetches a site, with its next and previous run
+type SiteSchedule struct {
+	SiteID          int        `json:"site_id"`
+	SiteName        string     `json:"site_name"`
+	Schedule        string     `json:"schedule"` // empty when the site is only fetched on request
+	JitterSeconds   int        `json:"jitter_seconds"`
+	QuietHoursStart *int       `json:"quiet_hours_start"`
+	QuietHoursEnd   *int       `json:"quiet_hours_end"`
+	Registered      bool       `json:"registered"` // false when no site client runs the schedule
+	NextRun         *time.Time `json:"next_run"`
+	PrevRun         *time.Time `json:"prev_run"`
+}
+
+// UpdateScheduleRequest represents the schedule of one site in the request body
+// for updating schedules
+type UpdateScheduleRequest struct {
+	SiteID          int    `json:"site_id" binding:"required"`
+	Schedule        string `json:"schedule"`
+	JitterSeconds   int    `json:"jitter_seconds"`
+	QuietHoursStart *int   `json:"quiet_hours_start"`
+	QuietHoursEnd   *int   `json:"quiet_hours_end"`
+}
+
+// Validate checks the cron expression, the jitter and the quiet hours
+func (r UpdateScheduleRequest) Validate() error {
+	if r.Schedule != "" {
+		if _, err := ScheduleParser.Parse(r.Schedule); err != nil {
+			return fmt.Errorf("invalid schedule '%s': %w", r.Schedule, err)
+		}
+	}
+	if r.JitterSeconds < 0 {
+		return errors.New("jitter_seconds must not be negative")
+	}
+	if (r.QuietHoursStart == nil) != (r.QuietHoursEnd == nil) {
+		return errors.New("quiet_hours_start and quiet_hours_end must be set together")
+	}
+	if r.QuietHoursStart != nil {
+		start, end := *r.QuietHoursStart, *r.QuietHoursEnd
+		if start < 0 || start > 23 || end < 0 || end > 23 {
+			return errors.New("quiet hours must be between 0 and 23")
+		}
+		if start == end {
+			return errors.New("quiet_hours_start and quiet_hours_end must differ")
+		}
+	}
+	return nil
+}
+
+// InQuietHours reports whether t falls in the quiet hours of the site, which may
+// wrap around midnight like 22 to 6
+func (s Site) InQuietHours(t time.Time) bool {
+	if s.QuietHoursStart == nil || s.QuietHoursEnd == nil {
+		return false
+	}
+	start, end, hour := *s.QuietHoursStart, *s.QuietHoursEnd, t.Hour()
+	if start < end {
+		return hour >= start && hour < end
+	}
+	return hour >= start || hour < end
+}
@@ -2,19 +2,37 @@ package models
 
 // Site represents a parts supplier website
 type Site struct {
-	ID   int    `json:"id"`
-	Name string `json:"name"`
-	URL  string `json:"url"`
+	ID           int    `json:"id"`
+	Name         string `json:"name"`
+	URL          string `json:"url"`
+	ClientType   string `json:"client_type"`
+	ClientConfig string `json:"client_config"`
+
+	// Lifecycle of the parts of the site: after how long without being seen they
+	// are missing and gone, and how long gone parts are kept (0 is forever)
+	MissingAfterHours int `json:"missing_after_hours"`
+	GoneAfterHours    int `json:"gone_after_hours"`
+	PurgeAfterDays    int `json:"purge_after_days"`
+
+	// When the scheduler fetches the site, see SiteSchedule
+	Schedule              string `json:"schedule"`
+	ScheduleJitterSeconds int    `json:"schedule_jitter_seconds"`
+	QuietHoursStart       *int   `json:"quiet_hours_start"`
+	QuietHoursEnd         *int   `json:"quiet_hours_end"`
 }
 
 // CreateSiteRequest represents the request body for creating a site
 type CreateSiteRequest struct {
-	Name string `json:"name" binding:"required"`
-	URL  string `json:"url" binding:"required"`
+	Name         string `json:"name" binding:"required"`
+	URL          string `json:"url" binding:"required"`
+	ClientType   string `json:"client_type"`
+	ClientConfig string `json:"client_config"`
 }
 
 // UpdateSiteRequest represents the request body for updating a site
 type UpdateSiteRequest struct {
-	Name string `json:"name" binding:"required"`
-	URL  string `json:"url" binding:"required"`
+	Name         string `json:"name" binding:"required"`
+	URL          string `json:"url" binding:"required"`
+	ClientType   string `json:"client_type"`
+	ClientConfig string `json:"client_config"`
 }
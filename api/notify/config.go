@@ -0,0 +1,141 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/template"
+)
+
+// Destination types
+const (
+	TypeWebhook = "webhook"
+	TypeSMTP    = "smtp"
+)
+
+// Config lists the destinations notifications are sent to
+type Config struct {
+	Destinations []DestinationConfig `json:"destinations"`
+}
+
+// DestinationConfig configures one destination. Subject and body are text/template
+// templates executed with the Event; they default to its Subject and Text.
+type DestinationConfig struct {
+	Name            string   `json:"name"`
+	Type            string   `json:"type"`
+	Events          []string `json:"events"` // kinds of events to send, all when empty
+	SubjectTemplate string   `json:"subject_template"`
+	BodyTemplate    string   `json:"body_template"`
+	Retries         *int     `json:"retries"` // defaults to 3
+
+	// Webhook
+	URL     string            `json:"url"`
+	Format  string            `json:"format"` // json, discord or slack
+	Headers map[string]string `json:"headers"`
+
+	// SMTP
+	Host     string   `json:"host"`
+	Port     int      `json:"port"`
+	Username string   `json:"username"`
+	Password string   `json:"password"`
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+}
+
+// LoadFile creates a Dispatcher from a JSON config file
+func LoadFile(path string) (*Dispatcher, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read notification config: %w", err)
+	}
+
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("invalid notification config: %w", err)
+	}
+	return New(config)
+}
+
+// New creates a Dispatcher for the destinations in config
+func New(config Config) (*Dispatcher, error) {
+	destinations := make([]*destination, 0, len(config.Destinations))
+	for i, destConfig := range config.Destinations {
+		if destConfig.Name == "" {
+			destConfig.Name = fmt.Sprintf("%s #%d", destConfig.Type, i+1)
+		}
+		dest, err := newDestination(destConfig)
+		if err != nil {
+			return nil, fmt.Errorf("destination '%s': %w", destConfig.Name, err)
+		}
+		destinations = append(destinations, dest)
+	}
+	return newDispatcher(destinations), nil
+}
+
+// newDestination creates the notifier of a destination and parses its templates
+func newDestination(config DestinationConfig) (*destination, error) {
+	var notifier Notifier
+	var err error
+	switch config.Type {
+	case TypeWebhook:
+		notifier, err = NewWebhook(config.URL, config.Format, config.Headers)
+	case TypeSMTP:
+		notifier, err = NewSMTP(config.Host, config.Port, config.Username, config.Password, config.From, config.To)
+	default:
+		err = fmt.Errorf("unknown type '%s', expected webhook or smtp", config.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	subject, err := parseTemplate("subject", config.SubjectTemplate, "{{.Subject}}")
+	if err != nil {
+		return nil, err
+	}
+	body, err := parseTemplate("body", config.BodyTemplate, "{{.Text}}")
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(map[string]bool)
+	for _, kind := range config.Events {
+		if kind != EventNewParts && kind != EventFetchFailed {
+			return nil, fmt.Errorf("unknown event '%s', expected %s or %s", kind, EventNewParts, EventFetchFailed)
+		}
+		events[kind] = true
+	}
+
+	retries := defaultRetries
+	if config.Retries != nil {
+		retries = max(0, *config.Retries)
+	}
+
+	return &destination{
+		name:     config.Name,
+		notifier: notifier,
+		events:   events,
+		subject:  subject,
+		body:     body,
+		retries:  retries,
+	}, nil
+}
+
+// parseTemplate parses a template, using fallback when text is empty
+func parseTemplate(name, text, fallback string) (*template.Template, error) {
+	if text == "" {
+		text = fallback
+	}
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s template: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+// Len returns the number of destinations
+func (d *Dispatcher) Len() int {
+	if d == nil {
+		return 0
+	}
+	return len(d.destinations)
+}
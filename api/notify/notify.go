@@ -0,0 +1,173 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// Event kinds
+const (
+	EventNewParts    = "new_parts"    // a digest of new parts matching saved searches
+	EventFetchFailed = "fetch_failed" // fetching from a site failed
+)
+
+// Event is something that happened that destinations are told about. Subject and
+// Text are its default rendering; templates can use the other fields instead.
+type Event struct {
+	Kind    string    `json:"kind"`
+	Subject string    `json:"subject"`
+	Text    string    `json:"text"`
+	Time    time.Time `json:"time"`
+	Parts   []Part    `json:"parts,omitempty"` // new_parts
+	Site    string    `json:"site,omitempty"`  // fetch_failed
+	Error   string    `json:"error,omitempty"` // fetch_failed
+}
+
+// Part is a part in a new_parts digest
+type Part struct {
+	ID     int    `json:"id"`
+	Search string `json:"search"`
+	Name   string `json:"name"`
+	Price  string `json:"price"`
+	URL    string `json:"url"`
+}
+
+// Message is an event rendered with the templates of a destination
+type Message struct {
+	Event   Event
+	Subject string
+	Body    string
+}
+
+// Notifier delivers messages to one destination, like a webhook or a mailbox
+type Notifier interface {
+	Notify(ctx context.Context, message Message) error
+}
+
+const (
+	// defaultRetries is the number of times a failed delivery is retried
+	defaultRetries = 3
+
+	// retryDelay is the wait before the first retry; it doubles for every next one
+	retryDelay = 5 * time.Second
+
+	// deliveryTimeout bounds a single delivery attempt
+	deliveryTimeout = 30 * time.Second
+)
+
+// destination is a notifier with the events it wants and how to render them
+type destination struct {
+	name     string
+	notifier Notifier
+	events   map[string]bool
+	subject  *template.Template
+	body     *template.Template
+	retries  int
+}
+
+// wants reports whether the destination wants events of a kind; no events means all
+func (d *destination) wants(kind string) bool {
+	return len(d.events) == 0 || d.events[kind]
+}
+
+// render renders an event with the templates of the destination
+func (d *destination) render(event Event) (Message, error) {
+	var subject, body bytes.Buffer
+	if err := d.subject.Execute(&subject, event); err != nil {
+		return Message{}, fmt.Errorf("failed to render subject: %w", err)
+	}
+	if err := d.body.Execute(&body, event); err != nil {
+		return Message{}, fmt.Errorf("failed to render body: %w", err)
+	}
+	return Message{Event: event, Subject: subject.String(), Body: body.String()}, nil
+}
+
+// Dispatcher sends events to every destination that wants them, in the background
+// and with retries. A nil Dispatcher sends nothing, so callers need no checks when
+// notifications are not configured.
+type Dispatcher struct {
+	destinations []*destination
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// newDispatcher creates a Dispatcher for the given destinations
+func newDispatcher(destinations []*destination) *Dispatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Dispatcher{
+		destinations: destinations,
+		ctx:          ctx,
+		cancel:       cancel,
+	}
+}
+
+// Notify sends an event to the destinations that want it without waiting for the
+// deliveries. Failed deliveries are retried and logged.
+func (d *Dispatcher) Notify(event Event) {
+	if d == nil {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	for _, dest := range d.destinations {
+		if !dest.wants(event.Kind) {
+			continue
+		}
+		d.wg.Add(1)
+		go func(dest *destination) {
+			defer d.wg.Done()
+			if err := d.deliver(dest, event); err != nil {
+				log.Printf("[Notify] WARNING: Failed to send %s to '%s': %v", event.Kind, dest.name, err)
+			}
+		}(dest)
+	}
+}
+
+// deliver renders an event for a destination and sends it, retrying failed attempts
+func (d *Dispatcher) deliver(dest *destination, event Event) error {
+	message, err := dest.render(event)
+	if err != nil {
+		return err
+	}
+
+	delay := retryDelay
+	for attempt := 0; ; attempt++ {
+		// An attempt in progress is finished on Close, only retries are cancelled
+		ctx, cancel := context.WithTimeout(context.Background(), deliveryTimeout)
+		err = dest.notifier.Notify(ctx, message)
+		cancel()
+		if err == nil {
+			log.Printf("[Notify] Sent %s to '%s'", event.Kind, dest.name)
+			return nil
+		}
+		if attempt >= dest.retries {
+			return fmt.Errorf("giving up after %d attempts: %w", attempt+1, err)
+		}
+
+		log.Printf("[Notify] Sending %s to '%s' failed, retrying in %v: %v", event.Kind, dest.name, delay, err)
+		select {
+		case <-d.ctx.Done():
+			return fmt.Errorf("retries cancelled by shutdown: %w", err)
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// Close waits for deliveries in progress, cancelling their retries
+func (d *Dispatcher) Close() {
+	if d == nil {
+		return
+	}
+	d.cancel()
+	d.wg.Wait()
+}
@@ -0,0 +1,97 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Webhook payload formats
+const (
+	FormatJSON    = "json"    // the event with the rendered subject and body
+	FormatDiscord = "discord" // a Discord webhook message
+	FormatSlack   = "slack"   // a Slack incoming webhook message
+)
+
+// discordMaxContent is the length limit of a Discord message
+const discordMaxContent = 2000
+
+// Webhook posts messages as JSON to a URL
+type Webhook struct {
+	URL     string
+	Format  string
+	Headers map[string]string
+	client  *http.Client
+}
+
+// NewWebhook creates a Webhook posting payloads in the given format
+func NewWebhook(url, format string, headers map[string]string) (*Webhook, error) {
+	if url == "" {
+		return nil, fmt.Errorf("webhook URL is required")
+	}
+	switch format {
+	case "":
+		format = FormatJSON
+	case FormatJSON, FormatDiscord, FormatSlack:
+	default:
+		return nil, fmt.Errorf("unknown webhook format '%s', expected json, discord or slack", format)
+	}
+	return &Webhook{
+		URL:     url,
+		Format:  format,
+		Headers: headers,
+		client:  &http.Client{Timeout: deliveryTimeout},
+	}, nil
+}
+
+// payload returns the JSON body for a message in the format of the webhook
+func (w *Webhook) payload(message Message) interface{} {
+	switch w.Format {
+	case FormatDiscord:
+		content := "**" + message.Subject + "**\n" + message.Body
+		if runes := []rune(content); len(runes) > discordMaxContent {
+			content = string(runes[:discordMaxContent-1]) + "…"
+		}
+		return map[string]string{"content": content}
+	case FormatSlack:
+		return map[string]string{"text": "*" + message.Subject + "*\n" + message.Body}
+	default:
+		return struct {
+			Event
+			Subject string `json:"subject"`
+			Body    string `json:"body"`
+		}{message.Event, message.Subject, message.Body}
+	}
+}
+
+// Notify posts a message to the webhook; any status other than 2xx is an error
+func (w *Webhook) Notify(ctx context.Context, message Message) error {
+	body, err := json.Marshal(w.payload(message))
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range w.Headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook returned status %d: %s", resp.StatusCode, bytes.TrimSpace(detail))
+	}
+	return nil
+}
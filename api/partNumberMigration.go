@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+
+	"dsmpartsfinder-api/partnumbers"
+
+	"github.com/pressly/goose/v3"
+)
+
+// newPartNumberMigration returns the Go migration that extracts the OEM part numbers
+// of the parts stored before part numbers were indexed
+func newPartNumberMigration() *goose.Migration {
+	return goose.NewGoMigration(20251101090100,
+		&goose.GoFunc{RunTx: migratePartNumbers},
+		nil,
+	)
+}
+
+// migratePartNumbers fills part_numbers from the name and description of every part
+func migratePartNumbers(ctx context.Context, tx *sql.Tx) error {
+	rows, err := tx.QueryContext(ctx, `SELECT id, name, description FROM parts`)
+	if err != nil {
+		return fmt.Errorf("failed to query parts: %w", err)
+	}
+
+	numbers := make(map[int][]string)
+	for rows.Next() {
+		var id int
+		var name, description string
+		if err := rows.Scan(&id, &name, &description); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan part: %w", err)
+		}
+		if found := partnumbers.Extract(name, description); len(found) > 0 {
+			numbers[id] = found
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read parts: %w", err)
+	}
+
+	for id, found := range numbers {
+		if err := replacePartNumbers(ctx, tx, id, found); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("[PartNumberMigration] Found part numbers in %d parts", len(numbers))
+	return nil
+}
@@ -0,0 +1,56 @@
+package partnumbers
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// mitsubishiNumber matches Mitsubishi part numbers like "MD123456", also written as
+// "MD 123 456" or "MD-123456". Sellers sometimes leave out the leading M, which is
+// only accepted when the number is written in one piece ("D123456").
+var mitsubishiNumber = regexp.MustCompile(`\b(?:M[ -]?([BDR])|([BDR]))[ -]?(\d{3})[ -]?(\d{3})\b`)
+
+// mitsubishiShortNumber matches the short form without the leading M, which must not
+// have separators to tell it apart from ordinary text like "R 123 456"
+var mitsubishiShortNumber = regexp.MustCompile(`^[BDR]\d{6}$`)
+
+// chryslerNumber matches the 7-digit Chrysler (Mopar) numbers of the Eagle Talon and
+// Plymouth Laser, like "4505432", often written with a leading zero and a revision
+// suffix as in "04505432AB" or "4505432-AB"
+var chryslerNumber = regexp.MustCompile(`\b0?([45]\d{6})(?:-?[A-Z]{2})?\b`)
+
+// Extract returns the normalised OEM part numbers found in the given texts, sorted
+// and without duplicates. Mitsubishi numbers are normalised to "MD123456", Chrysler
+// numbers to their seven digits without leading zero and revision suffix.
+func Extract(texts ...string) []string {
+	numbers := make([]string, 0)
+	for _, text := range texts {
+		text = strings.ToUpper(text)
+		for _, match := range mitsubishiNumber.FindAllStringSubmatch(text, -1) {
+			letter := match[1]
+			if letter == "" {
+				if !mitsubishiShortNumber.MatchString(match[0]) {
+					continue
+				}
+				letter = match[2]
+			}
+			numbers = append(numbers, "M"+letter+match[3]+match[4])
+		}
+		for _, match := range chryslerNumber.FindAllStringSubmatch(text, -1) {
+			numbers = append(numbers, match[1])
+		}
+	}
+	slices.Sort(numbers)
+	return slices.Compact(numbers)
+}
+
+// Normalise returns the normalised form of a single part number as given by a user,
+// like "md-123 456" for "MD123456". Numbers in no known format are returned upper
+// case without spaces and dashes, so they can still match exactly.
+func Normalise(number string) string {
+	if numbers := Extract(number); len(numbers) == 1 {
+		return numbers[0]
+	}
+	return strings.NewReplacer(" ", "", "-", "").Replace(strings.ToUpper(strings.TrimSpace(number)))
+}
@@ -0,0 +1,62 @@
+package partnumbers
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name  string
+		texts []string
+		want  []string
+	}{
+		{"none", []string{"Turbo TD05 16G"}, []string{}},
+		{"empty", nil, []string{}},
+		{"mitsubishi", []string{"Lader MD123456 gebraucht"}, []string{"MD123456"}},
+		{"mitsubishi lower case", []string{"md123456"}, []string{"MD123456"}},
+		{"mitsubishi with spaces", []string{"MD 123 456"}, []string{"MD123456"}},
+		{"mitsubishi with dashes", []string{"mr-123-456"}, []string{"MR123456"}},
+		{"mitsubishi with space after prefix", []string{"M B123456"}, []string{"MB123456"}},
+		{"short form", []string{"Nr. D123456"}, []string{"MD123456"}},
+		{"short form with separators is text", []string{"R 123 456"}, []string{}},
+		{"other prefix letter", []string{"MA123456"}, []string{}},
+		{"too many digits", []string{"MD1234567"}, []string{}},
+		{"inside a word", []string{"XMD123456"}, []string{}},
+		{"chrysler", []string{"Mopar 4505432"}, []string{"4505432"}},
+		{"chrysler with leading zero and revision", []string{"04505432AB"}, []string{"4505432"}},
+		{"chrysler with dashed revision", []string{"4505432-AB"}, []string{"4505432"}},
+		{"chrysler other first digit", []string{"3505432"}, []string{}},
+		{"eight digits", []string{"45054321"}, []string{}},
+		{"sorted without duplicates", []string{"MR123456 and MD654321", "md 654 321, 4505432"}, []string{"4505432", "MD654321", "MR123456"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Extract(tt.texts...); !slices.Equal(got, tt.want) {
+				t.Errorf("Extract(%q) = %q, want %q", tt.texts, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalise(t *testing.T) {
+	tests := []struct {
+		number string
+		want   string
+	}{
+		{"md-123 456", "MD123456"},
+		{" MD123456 ", "MD123456"},
+		{"D123456", "MD123456"},
+		{"04505432AB", "4505432"},
+		{"td05-16g", "TD0516G"},
+		{"1234 56", "123456"},
+		{"MD123456 MR654321", "MD123456MR654321"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.number, func(t *testing.T) {
+			if got := Normalise(tt.number); got != tt.want {
+				t.Errorf("Normalise(%q) = %q, want %q", tt.number, got, tt.want)
+			}
+		})
+	}
+}
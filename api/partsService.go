@@ -4,23 +4,50 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
+	"dsmpartsfinder-api/categories"
+	"dsmpartsfinder-api/duplicates"
+	"dsmpartsfinder-api/events"
+	"dsmpartsfinder-api/images"
+	"dsmpartsfinder-api/jobs"
 	. "dsmpartsfinder-api/models"
+	"dsmpartsfinder-api/notify"
+	"dsmpartsfinder-api/partnumbers"
 	"dsmpartsfinder-api/siteclients"
 )
 
 // PartsService manages the fetching and storage of parts from various site clients
 type PartsService struct {
-	sqlClient   *SQLClient
-	siteClients map[int]siteclients.SiteClient
+	sqlClient       *SQLClient
+	imageStore      *images.Store
+	imageDownloader *ImageDownloader
+	siteClients     map[int]siteclients.SiteClient
+	notifier        *notify.Dispatcher // nil when notifications are not configured
+	eventBus        *events.Bus
+	jobs            *jobs.Manager
+
+	// duplicatesMu keeps fetches of several sites from clustering at the same time
+	duplicatesMu sync.Mutex
+	// unhashable are the images that failed to be perceptually hashed, so they are
+	// not read again by every clustering; guarded by duplicatesMu
+	unhashable map[string]bool
 }
 
 // NewPartsService creates a new PartsService
-func NewPartsService(sqlClient *SQLClient) *PartsService {
+func NewPartsService(sqlClient *SQLClient, imageStore *images.Store, imageDownloader *ImageDownloader, notifier *notify.Dispatcher, eventBus *events.Bus, jobManager *jobs.Manager) *PartsService {
 	return &PartsService{
-		sqlClient:   sqlClient,
-		siteClients: make(map[int]siteclients.SiteClient),
+		sqlClient:       sqlClient,
+		imageStore:      imageStore,
+		imageDownloader: imageDownloader,
+		siteClients:     make(map[int]siteclients.SiteClient),
+		notifier:        notifier,
+		eventBus:        eventBus,
+		jobs:            jobManager,
+		unhashable:      make(map[string]bool),
 	}
 }
 
@@ -40,8 +67,114 @@ func (s *PartsService) GetSiteClient(siteID int) (siteclients.SiteClient, error)
 }
 
 // FetchAndStoreParts fetches parts from a site client and stores them in the database
-// It also updates last_seen for existing parts and optionally deletes stale parts
+// It also updates last_seen for existing parts and the status of parts no longer listed
 func (s *PartsService) FetchAndStoreParts(ctx context.Context, siteID int, params siteclients.SearchParams) ([]Part, error) {
+	parts, err := s.fetchAndStoreParts(ctx, siteID, params, 0, nil)
+	s.clusterDuplicates()
+	return parts, err
+}
+
+// FetchAndStorePartsForProfile fetches parts for a search profile and tags every
+// fetched part, new or existing, with the profile. The progress is recorded in
+// site, which may be nil.
+func (s *PartsService) FetchAndStorePartsForProfile(ctx context.Context, siteID int, profile SearchProfile, site *jobs.Site) ([]Part, error) {
+	return s.fetchAndStoreParts(ctx, siteID, profileSearchParams(profile), profile.ID, site)
+}
+
+// fetchJobTimeout bounds the fetches of a job
+const fetchJobTimeout = 5 * time.Minute
+
+// StartFetchJob fetches parts from the given sites concurrently in a background
+// job and returns it right away. The job fails when every site failed.
+func (s *PartsService) StartFetchJob(trigger string, siteIDs []int, params siteclients.SearchParams) *jobs.Job {
+	return s.jobs.Start(trigger, siteIDs, func(ctx context.Context, job *jobs.Job) error {
+		ctx, cancel := context.WithTimeout(ctx, fetchJobTimeout)
+		defer cancel()
+
+		log.Printf("[FetchJob %d] Fetching from %d site(s): %v", job.ID(), len(siteIDs), siteIDs)
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		failed := 0
+		for _, siteID := range siteIDs {
+			wg.Add(1)
+			go func(id int) {
+				defer wg.Done()
+				site := s.StartSiteFetch(job, id)
+				parts, err := s.fetchAndStoreParts(ctx, id, params, 0, site)
+				s.FinishSiteFetch(site, err)
+				if err != nil {
+					log.Printf("[FetchJob %d] ERROR fetching parts from site %d: %v", job.ID(), id, err)
+					mu.Lock()
+					failed++
+					mu.Unlock()
+					return
+				}
+				log.Printf("[FetchJob %d] Got %d new parts from site %d", job.ID(), len(parts), id)
+			}(siteID)
+		}
+		wg.Wait()
+		s.clusterDuplicates()
+
+		if failed == len(siteIDs) {
+			return fmt.Errorf("all %d site(s) failed", failed)
+		}
+		return nil
+	})
+}
+
+// StartSiteFetch marks the fetch of a site in a job as running and records it as
+// a fetch run
+func (s *PartsService) StartSiteFetch(job *jobs.Job, siteID int) *jobs.Site {
+	site := job.Site(siteID)
+	site.Start()
+
+	runID, err := s.sqlClient.CreateFetchRun(siteID, job.ID(), job.Trigger())
+	if err != nil {
+		log.Printf("[FetchJob %d] WARNING: Failed to record fetch run of site %d: %v", job.ID(), siteID, err)
+		return site
+	}
+	site.SetRunID(runID)
+	return site
+}
+
+// FinishSiteFetch marks the fetch of a site as done, failed with err when it is not
+// nil, and stores what it fetched in its fetch run
+func (s *PartsService) FinishSiteFetch(site *jobs.Site, err error) {
+	site.Finish(err)
+
+	progress := site.Progress()
+	if progress.RunID == 0 {
+		return
+	}
+	err = s.sqlClient.FinishFetchRun(FetchRun{
+		ID:            progress.RunID,
+		Status:        progress.Status,
+		PartsFetched:  progress.PartsFound,
+		PartsInserted: progress.PartsNew,
+		PartsUpdated:  progress.PartsUpdated,
+		PartsDeleted:  progress.PartsDeleted,
+		PartsFailed:   progress.PartsFailed,
+		Error:         strings.Join(progress.Errors, "; "),
+	})
+	if err != nil {
+		log.Printf("[FetchJob] WARNING: Failed to store fetch run %d: %v", progress.RunID, err)
+	}
+}
+
+// profileSearchParams converts a search profile into site client search parameters
+func profileSearchParams(profile SearchProfile) siteclients.SearchParams {
+	return siteclients.SearchParams{
+		VehicleType: profile.VehicleType,
+		Make:        profile.Make,
+		BaseModel:   profile.BaseModel,
+		Model:       profile.Model,
+		YearFrom:    profile.YearFrom,
+		YearTo:      profile.YearTo,
+		Limit:       profile.Limit,
+	}
+}
+
+func (s *PartsService) fetchAndStoreParts(ctx context.Context, siteID int, params siteclients.SearchParams, profileID int, site *jobs.Site) ([]Part, error) {
 	log.Printf("[FetchAndStoreParts] Starting fetch for site ID: %d with params: %+v", siteID, params)
 
 	// Get the appropriate site client
@@ -53,70 +186,232 @@ func (s *PartsService) FetchAndStoreParts(ctx context.Context, siteID int, param
 
 	log.Printf("[FetchAndStoreParts] Fetching parts from %s (site ID: %d)", client.GetName(), siteID)
 
-	// Fetch parts from the site
-	fetchedParts, err := client.FetchParts(ctx, params)
+	// Store every page as soon as the client hands it over, so a failure
+	// halfway through keeps the pages that were already fetched
+	storedParts := make([]Part, 0)
+	var fetchedCount, duplicateCount, errorCount, batchCount int
+	err = siteclients.StreamParts(ctx, client, params, func(batch []siteclients.Part) error {
+		batchCount++
+		fetchedCount += len(batch)
+		stored, duplicates, updated, errors, err := s.storeBatch(siteID, batch, profileID, site.RunID())
+		if err != nil {
+			return err
+		}
+		site.AddPage(len(batch), len(stored), updated, errors)
+		storedParts = append(storedParts, stored...)
+		duplicateCount += duplicates
+		errorCount += errors
+		log.Printf("[FetchAndStoreParts] Batch %d from %s: %d fetched, %d new, %d existing, %d errors",
+			batchCount, client.GetName(), len(batch), len(stored), duplicates, errors)
+		return nil
+	})
+	if err != nil {
+		log.Printf("[FetchAndStoreParts] ERROR: Failed to fetch parts from %s after %d batch(es), kept %d new parts: %v",
+			client.GetName(), batchCount, len(storedParts), err)
+		return storedParts, fmt.Errorf("failed to fetch parts from %s: %w", client.GetName(), err)
+	}
+
+	// Parts the site stopped listing go missing, then gone, and are eventually purged
+	gone, err := s.updatePartStatuses(siteID)
 	if err != nil {
-		log.Printf("[FetchAndStoreParts] ERROR: Failed to fetch parts from %s: %v", client.GetName(), err)
-		return nil, fmt.Errorf("failed to fetch parts from %s: %w", client.GetName(), err)
+		log.Printf("[FetchAndStoreParts] WARNING: Failed to update part statuses: %v", err)
+	}
+	site.AddDeleted(gone)
+
+	log.Printf("[FetchAndStoreParts] Successfully stored %d new parts, skipped %d duplicates, %d errors out of %d fetched",
+		len(storedParts), duplicateCount, errorCount, fetchedCount)
+
+	return storedParts, nil
+}
+
+// updatePartStatuses applies the lifecycle thresholds of a site to its parts and
+// returns how many went gone
+func (s *PartsService) updatePartStatuses(siteID int) (int, error) {
+	site, err := s.sqlClient.GetSiteByID(siteID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get site %d: %w", siteID, err)
+	}
+
+	now := time.Now()
+	missing, goneIDs, err := s.sqlClient.UpdatePartStatuses(siteID,
+		now.Add(-time.Duration(site.MissingAfterHours)*time.Hour),
+		now.Add(-time.Duration(site.GoneAfterHours)*time.Hour),
+	)
+	if err != nil {
+		return 0, err
+	}
+	log.Printf("[FetchAndStoreParts] Marked %d parts missing and %d parts gone for site ID %d", missing, len(goneIDs), siteID)
+
+	removed := make([]events.Event, len(goneIDs))
+	for i, id := range goneIDs {
+		removed[i] = events.Event{Type: events.PartRemoved, PartID: id, SiteID: siteID}
+	}
+	s.eventBus.Publish(removed...)
+
+	if site.PurgeAfterDays > 0 {
+		purged, err := s.sqlClient.PurgeGoneParts(siteID, now.AddDate(0, 0, -site.PurgeAfterDays))
+		if err != nil {
+			return len(goneIDs), err
+		}
+		log.Printf("[FetchAndStoreParts] Purged %d gone parts for site ID %d", purged, siteID)
+	}
+	return len(goneIDs), nil
+}
+
+// clusterDuplicates clusters the parts stored by a fetch with their listings on
+// other sites. It runs once per fetch job rather than per site and profile, as it
+// clusters all parts again.
+func (s *PartsService) clusterDuplicates() {
+	if err := s.DetectDuplicates(); err != nil {
+		log.Printf("[FetchAndStoreParts] WARNING: Failed to detect duplicates: %v", err)
+	}
+}
+
+// perceptualHashBatchSize is the number of images hashed per clustering, so a
+// database full of unhashed images is worked off over several fetches
+const perceptualHashBatchSize = 500
+
+// DetectDuplicates clusters the listings of the same part on different sites. All
+// parts that are not gone are clustered again, so clusters follow edited listings
+// and images downloaded since the last run.
+func (s *PartsService) DetectDuplicates() error {
+	s.duplicatesMu.Lock()
+	defer s.duplicatesMu.Unlock()
+
+	if err := s.hashImages(); err != nil {
+		log.Printf("[DetectDuplicates] WARNING: Failed to hash images: %v", err)
+	}
+
+	listings, err := s.sqlClient.GetDuplicateListings()
+	if err != nil {
+		return fmt.Errorf("failed to get parts: %w", err)
+	}
+
+	clusters := duplicates.Cluster(listings)
+	changed, err := s.sqlClient.SetClusters(clusters)
+	if err != nil {
+		return fmt.Errorf("failed to store clusters: %w", err)
+	}
+
+	log.Printf("[DetectDuplicates] %d of %d parts have duplicates, %d changed cluster", len(clusters), len(listings), changed)
+	return nil
+}
+
+// hashImages computes the missing perceptual hashes of up to perceptualHashBatchSize
+// stored images. Images that cannot be read or decoded are skipped until restart.
+func (s *PartsService) hashImages() error {
+	imageHashes, err := s.sqlClient.GetUnhashedImages()
+	if err != nil {
+		return err
 	}
 
-	log.Printf("[FetchAndStoreParts] Fetched %d parts from %s", len(fetchedParts), client.GetName())
+	hashed, attempted := 0, 0
+	for _, imageHash := range imageHashes {
+		if attempted == perceptualHashBatchSize {
+			break
+		}
+		if s.unhashable[imageHash] {
+			continue
+		}
+		attempted++
+
+		data, err := s.imageStore.Read(imageHash)
+		if err != nil {
+			s.unhashable[imageHash] = true
+			continue
+		}
+		perceptualHash, err := images.PerceptualHash(data)
+		if err != nil {
+			s.unhashable[imageHash] = true
+			continue
+		}
+		if err := s.sqlClient.SetPerceptualHash(imageHash, perceptualHash); err != nil {
+			return err
+		}
+		hashed++
+	}
 
-	if len(fetchedParts) > 0 {
-		log.Printf("[FetchAndStoreParts] First part example: ID=%s, Name=%s, Type=%s",
-			fetchedParts[0].ID, fetchedParts[0].Name, fetchedParts[0].TypeName)
+	if attempted > 0 {
+		log.Printf("[DetectDuplicates] Hashed %d of %d images", hashed, attempted)
 	}
+	return nil
+}
+
+// storeBatch stores one batch of fetched parts: existing parts get their last_seen
+// and changed fields updated, new parts are inserted. It returns the inserted parts
+// and the number of existing parts, of those updated and of insert errors. Images
+// queued for download count their failures for the fetch run runID.
+func (s *PartsService) storeBatch(siteID int, fetchedParts []siteclients.Part, profileID, runID int) ([]Part, int, int, int, error) {
+	classifyParts(fetchedParts)
 
 	// Check which parts already exist in the database
-	log.Printf("[FetchAndStoreParts] Checking for existing parts in database")
 	partIDs := make([]string, len(fetchedParts))
 	for i, part := range fetchedParts {
 		partIDs[i] = part.ID
 	}
 
-	existingParts, err := s.sqlClient.GetExistingPartIDs(partIDs, siteID)
+	existingParts, err := s.sqlClient.GetExistingParts(partIDs, siteID)
 	if err != nil {
 		log.Printf("[FetchAndStoreParts] ERROR: Failed to check existing parts: %v", err)
-		return nil, fmt.Errorf("failed to check existing parts: %w", err)
+		return nil, 0, 0, 0, fmt.Errorf("failed to check existing parts: %w", err)
 	}
 
-	duplicateCount := len(existingParts)
-	log.Printf("[FetchAndStoreParts] Found %d existing parts, %d new parts to insert", duplicateCount, len(fetchedParts)-duplicateCount)
-
 	// Update last_seen for existing parts
 	if len(existingParts) > 0 {
 		existingPartIDs := make([]string, 0, len(existingParts))
 		for partID := range existingParts {
 			existingPartIDs = append(existingPartIDs, partID)
 		}
-		log.Printf("[FetchAndStoreParts] Updating last_seen for %d existing parts", len(existingPartIDs))
 		if err := s.sqlClient.UpdateLastSeen(existingPartIDs, siteID); err != nil {
 			log.Printf("[FetchAndStoreParts] WARNING: Failed to update last_seen: %v", err)
 			// Don't fail the entire operation, just log the error
 		}
 	}
 
-	// Delete stale parts (last seen more than 3 days ago)
-	olderThan := time.Now().AddDate(0, 0, -3)
-	deletedCount, err := s.sqlClient.DeleteStaleParts(siteID, olderThan)
-	if err != nil {
-		log.Printf("[FetchAndStoreParts] WARNING: Failed to delete stale parts: %v", err)
-	} else {
-		log.Printf("[FetchAndStoreParts] Deleted %d stale parts for site ID %d", deletedCount, siteID)
+	// Write the changes of existing parts that were edited on the site
+	editedIDs := make(map[string]bool)
+	pendingImages := make([]PendingImage, 0)
+	for _, part := range fetchedParts {
+		existing, exists := existingParts[part.ID]
+		// A page can list the same part twice; only record its changes once
+		if !exists || editedIDs[part.ID] {
+			continue
+		}
+		fields := changedFields(existing, part)
+		if len(fields) == 0 {
+			continue
+		}
+		updated := mergeFetchedPart(existing, part)
+		var priceAmount int64
+		if updated.PriceAmount != nil {
+			priceAmount = *updated.PriceAmount
+		}
+		err := s.sqlClient.UpdatePart(existing.ID, updated.Description, updated.TypeName, updated.Name, updated.ImageSourceURL, updated.URL,
+			updated.Price, priceAmount, updated.PriceCurrency, updated.PriceType, fields)
+		if err != nil {
+			log.Printf("[FetchAndStoreParts] WARNING: Failed to update part %s: %v", part.ID, err)
+			continue
+		}
+		editedIDs[part.ID] = true
+		if slices.Contains(fields, FieldName) || slices.Contains(fields, FieldDescription) {
+			s.storePartNumbers(existing.ID, updated.Name, updated.Description)
+		}
+		if slices.Contains(fields, FieldImage) {
+			pendingImages = append(pendingImages, PendingImage{PartID: existing.ID, URL: updated.ImageSourceURL, RunID: runID})
+		}
+	}
+	if len(editedIDs) > 0 {
+		log.Printf("[FetchAndStoreParts] Updated %d edited existing parts", len(editedIDs))
+		s.publishUpdatedParts(existingParts, editedIDs)
 	}
 
 	// Store only new parts in the database
-	log.Printf("[FetchAndStoreParts] Starting to store new parts in database")
-	storedParts := make([]Part, 0, len(fetchedParts)-duplicateCount)
+	storedParts := make([]Part, 0, len(fetchedParts)-len(existingParts))
 	errorCount := 0
-	insertedCount := 0
 
 	for i, part := range fetchedParts {
 		// Skip if part already exists
-		if existingParts[part.ID] {
-			if i < 3 { // Log first 3 skipped duplicates
-				log.Printf("[FetchAndStoreParts] Skipping duplicate part: ID=%s, Name=%s", part.ID, part.Name)
-			}
+		if _, exists := existingParts[part.ID]; exists {
 			continue
 		}
 
@@ -126,10 +421,13 @@ func (s *PartsService) FetchAndStoreParts(ctx context.Context, siteID int, param
 			part.Description,
 			part.TypeName,
 			part.Name,
-			part.ImageBase64,
+			part.ImageURL,
 			part.URL,
 			part.SiteID,
 			part.Price,
+			part.PriceAmount,
+			part.PriceCurrency,
+			string(part.PriceType),
 			part.CreationDate,
 		)
 		if err != nil {
@@ -140,17 +438,253 @@ func (s *PartsService) FetchAndStoreParts(ctx context.Context, siteID int, param
 			errorCount++
 			continue
 		}
+		storedPart.PartNumbers = s.storePartNumbers(storedPart.ID, part.Name, part.Description)
 		storedParts = append(storedParts, *storedPart)
-		insertedCount++
-		if insertedCount <= 3 { // Log first 3 successful stores
-			log.Printf("[FetchAndStoreParts] Successfully stored part: ID=%s, DB_ID=%d, Name=%s", part.ID, storedPart.ID, storedPart.Name)
+		if part.ImageURL != "" {
+			pendingImages = append(pendingImages, PendingImage{PartID: storedPart.ID, URL: part.ImageURL, RunID: runID})
 		}
 	}
 
-	log.Printf("[FetchAndStoreParts] Successfully stored %d new parts, skipped %d duplicates, %d errors out of %d fetched",
-		len(storedParts), duplicateCount, errorCount, len(fetchedParts))
+	created := make([]events.Event, len(storedParts))
+	for i := range storedParts {
+		created[i] = events.Event{Type: events.PartCreated, PartID: storedParts[i].ID, SiteID: siteID, Part: &storedParts[i]}
+	}
+	s.eventBus.Publish(created...)
 
-	return storedParts, nil
+	// Images are downloaded in the background; what does not fit in the queue is picked up by the next sweep
+	s.imageDownloader.Enqueue(pendingImages...)
+
+	if profileID != 0 {
+		if err := s.sqlClient.TagPartsWithProfile(partIDs, siteID, profileID); err != nil {
+			log.Printf("[FetchAndStoreParts] WARNING: Failed to tag parts with profile %d: %v", profileID, err)
+		}
+	}
+
+	// Matched after tagging, so saved searches can filter on the profile
+	if err := s.matchSavedSearches(storedParts); err != nil {
+		log.Printf("[FetchAndStoreParts] WARNING: Failed to match saved searches: %v", err)
+	}
+
+	return storedParts, len(existingParts), len(editedIDs), errorCount, nil
+}
+
+// publishUpdatedParts publishes the edited existing parts as they are stored now
+func (s *PartsService) publishUpdatedParts(existingParts map[string]Part, editedIDs map[string]bool) {
+	updated := make([]events.Event, 0, len(editedIDs))
+	for partID := range editedIDs {
+		part, err := s.sqlClient.GetPartByID(existingParts[partID].ID)
+		if err != nil {
+			log.Printf("[FetchAndStoreParts] WARNING: Failed to load updated part %s: %v", partID, err)
+			continue
+		}
+		updated = append(updated, events.Event{Type: events.PartUpdated, PartID: part.ID, SiteID: part.SiteID, Part: part})
+	}
+	s.eventBus.Publish(updated...)
+}
+
+// matchSavedSearches records an alert for every new part that matches an enabled saved search
+func (s *PartsService) matchSavedSearches(parts []Part) error {
+	if len(parts) == 0 {
+		return nil
+	}
+
+	searches, err := s.sqlClient.GetAllSavedSearches(true)
+	if err != nil {
+		return fmt.Errorf("failed to get saved searches: %w", err)
+	}
+
+	partIDs := make([]int, len(parts))
+	for i, part := range parts {
+		partIDs[i] = part.ID
+	}
+
+	for _, search := range searches {
+		created, err := s.sqlClient.CreateAlerts(search.ID, search.Filter(), partIDs)
+		if err != nil {
+			return fmt.Errorf("failed to match saved search %d: %w", search.ID, err)
+		}
+		if created > 0 {
+			log.Printf("[FetchAndStoreParts] Saved search '%s' matched %d new parts", search.Name, created)
+		}
+	}
+	return nil
+}
+
+// digestLimit is the maximum number of parts listed in a new parts digest
+const digestLimit = 100
+
+// SendNewPartsDigest notifies about the unread alerts created since a time, in one
+// digest for all saved searches. Nothing is sent when there are none.
+func (s *PartsService) SendNewPartsDigest(since time.Time) error {
+	if s.notifier.Len() == 0 {
+		return nil
+	}
+
+	filter := AlertsFilter{UnreadOnly: true, CreatedSince: since}
+	total, err := s.sqlClient.GetAlertsCount(filter)
+	if err != nil {
+		return fmt.Errorf("failed to count new alerts: %w", err)
+	}
+	if total == 0 {
+		return nil
+	}
+	alerts, err := s.sqlClient.GetAlerts(digestLimit, 0, filter)
+	if err != nil {
+		return fmt.Errorf("failed to get new alerts: %w", err)
+	}
+
+	event := notify.Event{
+		Kind:    notify.EventNewParts,
+		Subject: fmt.Sprintf("%d new parts match your saved searches", total),
+		Parts:   make([]notify.Part, 0, len(alerts)),
+	}
+	var text strings.Builder
+	for _, alert := range alerts {
+		if alert.Part == nil {
+			continue
+		}
+		part := notify.Part{
+			ID:     alert.Part.ID,
+			Search: alert.SavedSearchName,
+			Name:   alert.Part.Name,
+			Price:  alert.Part.Price,
+			URL:    alert.Part.URL,
+		}
+		event.Parts = append(event.Parts, part)
+		fmt.Fprintf(&text, "[%s] %s", part.Search, part.Name)
+		if part.Price != "" {
+			fmt.Fprintf(&text, " - %s", part.Price)
+		}
+		fmt.Fprintf(&text, "\n%s\n", part.URL)
+	}
+	if total > len(event.Parts) {
+		fmt.Fprintf(&text, "... and %d more\n", total-len(event.Parts))
+	}
+	event.Text = text.String()
+
+	s.notifier.Notify(event)
+	return nil
+}
+
+// NotifyFetchFailed warns that fetching from a site failed
+func (s *PartsService) NotifyFetchFailed(siteID int, fetchErr error) {
+	site := fmt.Sprintf("site %d", siteID)
+	if client, err := s.GetSiteClient(siteID); err == nil {
+		site = client.GetName()
+	}
+	s.notifier.Notify(notify.Event{
+		Kind:    notify.EventFetchFailed,
+		Subject: fmt.Sprintf("Fetching from %s failed", site),
+		Text:    fetchErr.Error(),
+		Site:    site,
+		Error:   fetchErr.Error(),
+	})
+}
+
+// classifyParts sets the type of fetched parts to their category in the part taxonomy
+func classifyParts(parts []siteclients.Part) {
+	for i := range parts {
+		parts[i].TypeName = categories.Classify(parts[i].Name, parts[i].Description)
+	}
+}
+
+// storePartNumbers extracts the OEM part numbers from the name and description of a
+// stored part and stores them, returning the numbers found
+func (s *PartsService) storePartNumbers(id int, name, description string) []string {
+	numbers := partnumbers.Extract(name, description)
+	if err := s.sqlClient.SetPartNumbers(id, numbers); err != nil {
+		log.Printf("[FetchAndStoreParts] WARNING: Failed to store part numbers of part %d: %v", id, err)
+	}
+	return numbers
+}
+
+// changedFields returns the listing fields of a stored part that differ from the
+// fetched part. Empty fetched fields are not compared, as sites leave out fields
+// on some pages rather than clear them.
+func changedFields(stored Part, fetched siteclients.Part) []string {
+	fields := make([]string, 0)
+	compare := func(field, storedValue, fetchedValue string) {
+		if fetchedValue != "" && storedValue != fetchedValue {
+			fields = append(fields, field)
+		}
+	}
+	compare(FieldName, stored.Name, fetched.Name)
+	compare(FieldDescription, stored.Description, fetched.Description)
+	compare(FieldURL, stored.URL, fetched.URL)
+	// Parts whose images were migrated from the database do not know where they came from
+	if stored.ImageSourceURL != "" {
+		compare(FieldImage, stored.ImageSourceURL, fetched.ImageURL)
+	}
+	if priceChanged(stored, fetched) {
+		fields = append(fields, FieldPrice)
+	}
+	return fields
+}
+
+// priceChanged reports whether the fetched price of a part differs from the stored
+// one. A page without a price leaves the stored price alone, like other empty fields.
+func priceChanged(stored Part, fetched siteclients.Part) bool {
+	if fetched.Price == "" {
+		return false
+	}
+	if stored.PriceType != string(fetched.PriceType) || stored.PriceCurrency != fetched.PriceCurrency {
+		return true
+	}
+	if stored.PriceAmount != nil && *stored.PriceAmount != fetched.PriceAmount {
+		return true
+	}
+	// Prices that could not be parsed can only be compared as text
+	return fetched.PriceType == "" && stored.Price != fetched.Price
+}
+
+// mergeFetchedPart returns the stored part with the non-empty listing fields of the fetched part
+func mergeFetchedPart(stored Part, fetched siteclients.Part) Part {
+	merge := func(storedValue *string, fetchedValue string) {
+		if fetchedValue != "" {
+			*storedValue = fetchedValue
+		}
+	}
+	merge(&stored.Name, fetched.Name)
+	merge(&stored.Description, fetched.Description)
+	// The type is ours rather than the site's, so it follows the fetched text unless set by hand
+	if !stored.TypeOverridden {
+		stored.TypeName = fetched.TypeName
+	}
+	merge(&stored.URL, fetched.URL)
+	merge(&stored.ImageSourceURL, fetched.ImageURL)
+	// A page without a price keeps the stored one
+	if fetched.Price != "" {
+		amount := fetched.PriceAmount
+		stored.Price = fetched.Price
+		stored.PriceAmount = &amount
+		stored.PriceCurrency = fetched.PriceCurrency
+		stored.PriceType = string(fetched.PriceType)
+	}
+	return stored
+}
+
+// QueueMissingImages queues the images of stored parts that have not been downloaded yet
+func (s *PartsService) QueueMissingImages() error {
+	return s.imageDownloader.EnqueuePending()
+}
+
+// imagePruneMinAge protects images saved by a running fetch whose parts are not stored yet
+const imagePruneMinAge = time.Hour
+
+// PruneImages deletes images from the image store that no part references anymore
+func (s *PartsService) PruneImages() error {
+	referenced, err := s.sqlClient.GetImageHashes()
+	if err != nil {
+		return fmt.Errorf("failed to get referenced images: %w", err)
+	}
+
+	removed, err := s.imageStore.Prune(referenced, imagePruneMinAge)
+	if err != nil {
+		return fmt.Errorf("failed to prune images: %w", err)
+	}
+
+	log.Printf("[PruneImages] Removed %d unreferenced images", removed)
+	return nil
 }
 
 // FetchPartsOnly fetches parts from a site client without storing them
@@ -194,10 +728,10 @@ func (s *PartsService) GetAllParts(limit, offset int) ([]Part, error) {
 }
 
 // GetFilteredParts retrieves filtered parts from the database
-func (s *PartsService) GetFilteredParts(limit, offset int, typeFilter string, siteIDs []int, newerThan time.Time, search string, sortBy string, sortDesc bool) ([]Part, error) {
-	log.Printf("[GetFilteredParts] Called with limit=%d, offset=%d, typeFilter=%s, siteIDs=%v, newerThan=%v, search=%s, sortBy=%s, sortDesc=%v",
-		limit, offset, typeFilter, siteIDs, newerThan, search, sortBy, sortDesc)
-	parts, err := s.sqlClient.GetFilteredParts(limit, offset, typeFilter, siteIDs, newerThan, search, sortBy, sortDesc)
+func (s *PartsService) GetFilteredParts(limit, offset int, filter PartsFilter, sortBy string, sortDesc bool) ([]Part, error) {
+	log.Printf("[GetFilteredParts] Called with limit=%d, offset=%d, filter=%+v, sortBy=%s, sortDesc=%v",
+		limit, offset, filter, sortBy, sortDesc)
+	parts, err := s.sqlClient.GetFilteredParts(limit, offset, filter, sortBy, sortDesc)
 	if err != nil {
 		log.Printf("[GetFilteredParts] ERROR: %v", err)
 		return nil, err
@@ -216,14 +750,13 @@ func (s *PartsService) GetTotalPartsCount() (int, error) {
 	return count, nil
 }
 
-func (s *PartsService) GetFilteredPartsCount(typeFilter string, siteIDs []int, newerThan time.Time, search string) (int, error) {
-	count, err := s.sqlClient.GetFilteredPartsCount(typeFilter, siteIDs, newerThan, search)
+func (s *PartsService) GetFilteredPartsCount(filter PartsFilter) (int, error) {
+	count, err := s.sqlClient.GetFilteredPartsCount(filter)
 	if err != nil {
 		log.Printf("[GetFilteredPartsCount] ERROR: %v", err)
 		return 0, err
 	}
-	log.Printf("[GetFilteredPartsCount] Total count: %d (typeFilter=%s, siteIDs=%v, newerThan=%v, search=%s)",
-		count, typeFilter, siteIDs, newerThan, search)
+	log.Printf("[GetFilteredPartsCount] Total count: %d (filter=%+v)", count, filter)
 	return count, nil
 }
 
@@ -246,3 +779,29 @@ func (s *PartsService) GetRegisteredSiteIDs() []int {
 	log.Printf("[GetRegisteredSiteIDs] Returning %d registered site IDs: %v", len(siteIDs), siteIDs)
 	return siteIDs
 }
+
+// GetRegisteredSites returns the stored sites that have a registered site client
+func (s *PartsService) GetRegisteredSites() ([]Site, error) {
+	sites, err := s.sqlClient.GetAllSites()
+	if err != nil {
+		return nil, err
+	}
+
+	registered := make([]Site, 0, len(sites))
+	for _, site := range sites {
+		if _, exists := s.siteClients[site.ID]; exists {
+			registered = append(registered, site)
+		}
+	}
+	return registered, nil
+}
+
+// GetEnabledProfiles returns the search profiles the scheduler should run
+func (s *PartsService) GetEnabledProfiles() ([]SearchProfile, error) {
+	return s.sqlClient.GetAllProfiles(true)
+}
+
+// GetLastFetchRun returns the newest fetch run of a site, or sql.ErrNoRows
+func (s *PartsService) GetLastFetchRun(siteID int) (*FetchRun, error) {
+	return s.sqlClient.GetLastFetchRun(siteID)
+}
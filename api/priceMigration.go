@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+
+	"dsmpartsfinder-api/siteclients"
+
+	"github.com/pressly/goose/v3"
+)
+
+// newPriceMigration returns the Go migration that parses the price text of the
+// parts stored before prices were structured. All sites so far listed in euros.
+func newPriceMigration() *goose.Migration {
+	return goose.NewGoMigration(20251025090100,
+		&goose.GoFunc{RunTx: migrateStructuredPrices},
+		nil,
+	)
+}
+
+// migrateStructuredPrices fills price_amount, price_currency and price_type from price
+func migrateStructuredPrices(ctx context.Context, tx *sql.Tx) error {
+	rows, err := tx.QueryContext(ctx, `SELECT id, price FROM parts WHERE price IS NOT NULL AND price != ''`)
+	if err != nil {
+		return fmt.Errorf("failed to query part prices: %w", err)
+	}
+
+	prices := make(map[int]siteclients.Price)
+	unparsed := 0
+	for rows.Next() {
+		var id int
+		var text string
+		if err := rows.Scan(&id, &text); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan part price: %w", err)
+		}
+		if price, ok := siteclients.ParsePrice(text, "EUR"); ok {
+			prices[id] = price
+		} else {
+			unparsed++
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read part prices: %w", err)
+	}
+
+	for id, price := range prices {
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE parts SET price_amount = ?, price_currency = ?, price_type = ? WHERE id = ?
+		`, priceAmountValue(price.Amount, string(price.Type)), price.Currency, string(price.Type), id); err != nil {
+			return fmt.Errorf("failed to update price of part %d: %w", id, err)
+		}
+	}
+
+	log.Printf("[PriceMigration] Parsed %d prices (%d unrecognised)", len(prices), unparsed)
+	return nil
+}
@@ -0,0 +1,125 @@
+package routes
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	. "dsmpartsfinder-api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// registerAlertRoutes registers the endpoints for the alerts of saved searches
+func registerAlertRoutes(api *gin.RouterGroup, sqlClient SQLClient) {
+	// GET /api/alerts - Get alerts with their parts, newest first
+	api.GET("/alerts", func(c *gin.Context) {
+		limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+		offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+		filter := parseAlertsFilter(c)
+
+		alerts, err := sqlClient.GetAlerts(limit, offset, filter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to query alerts",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		total, err := sqlClient.GetAlertsCount(filter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to count alerts",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		unreadFilter := filter
+		unreadFilter.UnreadOnly = true
+		unread, err := sqlClient.GetAlertsCount(unreadFilter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to count unread alerts",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"data":    alerts,
+			"message": "Alerts retrieved successfully",
+			"total":   total,
+			"unread":  unread,
+			"limit":   limit,
+			"offset":  offset,
+		})
+	})
+
+	// PUT /api/alerts/:id - Mark an alert read or unread
+	api.PUT("/alerts/:id", func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid alert ID",
+			})
+			return
+		}
+
+		var req UpdateAlertRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid request body",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		err = sqlClient.SetAlertRead(id, *req.Read)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Alert not found",
+			})
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to update alert",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message":  "Alert updated successfully",
+			"alert_id": id,
+			"read":     *req.Read,
+		})
+	})
+
+	// POST /api/alerts/read - Mark all alerts read, or those of one saved search
+	api.POST("/alerts/read", func(c *gin.Context) {
+		marked, err := sqlClient.MarkAlertsRead(parseAlertsFilter(c))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to mark alerts read",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Alerts marked read successfully",
+			"marked":  marked,
+		})
+	})
+}
+
+// parseAlertsFilter reads the alerts filter query parameters
+func parseAlertsFilter(c *gin.Context) AlertsFilter {
+	filter := AlertsFilter{
+		UnreadOnly: c.Query("unread") == "true",
+	}
+	filter.SavedSearchID, _ = strconv.Atoi(c.Query("saved_search_id"))
+	return filter
+}
@@ -0,0 +1,89 @@
+package routes
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"dsmpartsfinder-api/categories"
+	. "dsmpartsfinder-api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// registerCategoryRoutes registers the endpoints for the part taxonomy that the
+// type_name of every part is classified into
+func registerCategoryRoutes(api *gin.RouterGroup, sqlClient SQLClient) {
+	// GET /api/categories - Get the part taxonomy
+	api.GET("/categories", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"data":    categories.Taxonomy,
+			"message": "Categories retrieved successfully",
+			"total":   len(categories.Taxonomy),
+		})
+	})
+
+	// PUT /api/parts/:id/type - Set the type of a part by hand, or hand it back to the classifier
+	api.PUT("/parts/:id/type", func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid part ID",
+			})
+			return
+		}
+
+		var req SetPartTypeRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid request body",
+				"details": err.Error(),
+			})
+			return
+		}
+		if req.TypeName != "" && !categories.Valid(req.TypeName) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid part type",
+				"details": fmt.Sprintf("type '%s' is not in the part taxonomy, see /api/categories", req.TypeName),
+			})
+			return
+		}
+
+		part, err := sqlClient.SetPartType(id, req.TypeName)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Part not found",
+			})
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to set part type",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"data":    part,
+			"message": "Part type updated successfully",
+		})
+	})
+
+	// POST /api/parts/reclassify - Classify all parts whose type was not set by hand again
+	api.POST("/parts/reclassify", func(c *gin.Context) {
+		changed, err := sqlClient.ReclassifyParts(c.Request.Context())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to reclassify parts",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Parts reclassified successfully",
+			"changed": changed,
+		})
+	})
+}
@@ -0,0 +1,106 @@
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"dsmpartsfinder-api/events"
+	. "dsmpartsfinder-api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// keepAliveInterval is how often an idle event stream sends a comment, so proxies
+// do not close it
+const keepAliveInterval = 30 * time.Second
+
+type EventBus interface {
+	Subscribe(lastID int64) (*events.Subscription, []events.Event)
+}
+
+// registerEventRoutes registers the Server-Sent Events stream of part changes
+func registerEventRoutes(api *gin.RouterGroup, eventBus EventBus) {
+	// GET /api/events?site_ids[]=1&search=td05 - Stream part changes as they are stored
+	api.GET("/events", func(c *gin.Context) {
+		filter := events.Filter{
+			SiteIDs: ParsePartsFilter(c.Request.URL.Query()).SiteIDs,
+			Search:  c.Query("search"),
+		}
+
+		// Browsers send Last-Event-ID when reconnecting; the query parameter allows
+		// resuming a new EventSource
+		lastIDStr := c.GetHeader("Last-Event-ID")
+		if lastIDStr == "" {
+			lastIDStr = c.Query("last_event_id")
+		}
+		var lastID int64
+		if lastIDStr != "" {
+			var err error
+			lastID, err = strconv.ParseInt(lastIDStr, 10, 64)
+			if err != nil || lastID < 0 {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error": "Invalid Last-Event-ID",
+				})
+				return
+			}
+		}
+
+		sub, missed := eventBus.Subscribe(lastID)
+		defer sub.Close()
+
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		c.Header("X-Accel-Buffering", "no")
+		c.Status(http.StatusOK)
+
+		w := c.Writer
+		fmt.Fprint(w, "retry: 5000\n\n")
+		for _, event := range missed {
+			if filter.Matches(event) {
+				writeEvent(w, event)
+			}
+		}
+		w.Flush()
+
+		keepAlive := time.NewTicker(keepAliveInterval)
+		defer keepAlive.Stop()
+
+		for {
+			select {
+			case <-c.Request.Context().Done():
+				return
+			case event, ok := <-sub.Events:
+				// Closed when the client fell behind; it reconnects and resumes
+				if !ok {
+					return
+				}
+				if !filter.Matches(event) {
+					continue
+				}
+				if err := writeEvent(w, event); err != nil {
+					return
+				}
+			case <-keepAlive.C:
+				if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+					return
+				}
+			}
+			w.Flush()
+		}
+	})
+}
+
+// writeEvent writes an event in the Server-Sent Events format
+func writeEvent(w io.Writer, event events.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	return err
+}
@@ -0,0 +1,323 @@
+package routes
+
+import (
+	"encoding/xml"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	. "dsmpartsfinder-api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// defaultFeedEntries is the number of entries in a feed without a limit
+	defaultFeedEntries = 50
+
+	// maxFeedEntries caps the limit of a feed
+	maxFeedEntries = 200
+
+	feedTitle = "DSM Parts Finder"
+)
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Author  atomPerson  `xml:"author"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+type atomEntry struct {
+	Title     string        `xml:"title"`
+	ID        string        `xml:"id"`
+	Published string        `xml:"published"`
+	Updated   string        `xml:"updated"`
+	Author    atomPerson    `xml:"author"`
+	Links     []atomLink    `xml:"link"`
+	Category  *atomCategory `xml:"category,omitempty"`
+	Content   atomText      `xml:"content"`
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssGUID struct {
+	IsPermaLink string `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length string `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+type rssItem struct {
+	Title       string        `xml:"title"`
+	Link        string        `xml:"link"`
+	Description string        `xml:"description"`
+	GUID        rssGUID       `xml:"guid"`
+	PubDate     string        `xml:"pubDate"`
+	Category    string        `xml:"category,omitempty"`
+	Enclosure   *rssEnclosure `xml:"enclosure"`
+}
+
+// feedEntry is a part prepared for rendering as feed entry
+type feedEntry struct {
+	part      Part
+	id        string // absolute URL of the part in the API
+	title     string
+	site      string
+	thumbnail string // absolute URL, "" without image
+	content   string // HTML
+}
+
+// registerFeedRoutes registers Atom and RSS feeds of the newest parts matching the
+// filter parameters of GET /api/parts. Both support If-Modified-Since against the
+// created_at of the newest matching part.
+func registerFeedRoutes(api *gin.RouterGroup, sqlClient SQLClient) {
+	// GET /api/feeds/parts.atom - Atom feed of the newest matching parts
+	api.GET("/feeds/parts.atom", func(c *gin.Context) {
+		entries, updated, ok := loadFeedEntries(c, sqlClient)
+		if !ok {
+			return
+		}
+
+		// Atom requires an updated time, even for a feed without entries
+		if updated.IsZero() {
+			updated = time.Now()
+		}
+		feed := atomFeed{
+			Title:   feedTitleFor(c),
+			ID:      requestBaseURL(c) + c.Request.URL.RequestURI(),
+			Updated: updated.UTC().Format(time.RFC3339),
+			Author:  atomPerson{Name: feedTitle},
+			Links: []atomLink{
+				{Rel: "self", Type: "application/atom+xml", Href: requestBaseURL(c) + c.Request.URL.RequestURI()},
+				{Rel: "alternate", Type: "text/html", Href: requestBaseURL(c) + "/"},
+			},
+			Entries: make([]atomEntry, 0, len(entries)),
+		}
+		for _, entry := range entries {
+			atom := atomEntry{
+				Title:     entry.title,
+				ID:        entry.id,
+				Published: entry.part.CreatedAt.UTC().Format(time.RFC3339),
+				Updated:   entry.part.UpdatedAt.UTC().Format(time.RFC3339),
+				Author:    atomPerson{Name: entry.site},
+				Links:     []atomLink{{Rel: "alternate", Type: "text/html", Href: entry.part.URL}},
+				Content:   atomText{Type: "html", Body: entry.content},
+			}
+			if entry.thumbnail != "" {
+				atom.Links = append(atom.Links, atomLink{Rel: "enclosure", Type: "image/jpeg", Href: entry.thumbnail})
+			}
+			if entry.part.TypeName != "" {
+				atom.Category = &atomCategory{Term: entry.part.TypeName}
+			}
+			feed.Entries = append(feed.Entries, atom)
+		}
+
+		renderFeed(c, "application/atom+xml; charset=utf-8", feed)
+	})
+
+	// GET /api/feeds/parts.rss - RSS 2.0 feed of the newest matching parts
+	api.GET("/feeds/parts.rss", func(c *gin.Context) {
+		entries, updated, ok := loadFeedEntries(c, sqlClient)
+		if !ok {
+			return
+		}
+
+		feed := rssFeed{
+			Version: "2.0",
+			Channel: rssChannel{
+				Title:       feedTitleFor(c),
+				Link:        requestBaseURL(c) + "/",
+				Description: "Newest parts matching the query",
+				Items:       make([]rssItem, 0, len(entries)),
+			},
+		}
+		if !updated.IsZero() {
+			feed.Channel.LastBuildDate = updated.UTC().Format(time.RFC1123Z)
+		}
+		for _, entry := range entries {
+			item := rssItem{
+				Title:       entry.title,
+				Link:        entry.part.URL,
+				Description: entry.content,
+				GUID:        rssGUID{IsPermaLink: "false", Value: entry.id},
+				PubDate:     entry.part.CreatedAt.UTC().Format(time.RFC1123Z),
+				Category:    entry.part.TypeName,
+			}
+			if entry.thumbnail != "" {
+				item.Enclosure = &rssEnclosure{URL: entry.thumbnail, Length: "0", Type: "image/jpeg"}
+			}
+			feed.Channel.Items = append(feed.Channel.Items, item)
+		}
+
+		renderFeed(c, "application/rss+xml; charset=utf-8", feed)
+	})
+}
+
+// loadFeedEntries loads the newest parts matching the query of a feed request. It
+// answers the request itself and returns false on errors and when the feed has not
+// changed since If-Modified-Since.
+func loadFeedEntries(c *gin.Context, sqlClient SQLClient) ([]feedEntry, time.Time, bool) {
+	filter := ParsePartsFilter(c.Request.URL.Query())
+	limit, err := strconv.Atoi(c.Query("limit"))
+	if err != nil || limit < 1 {
+		limit = defaultFeedEntries
+	}
+	limit = min(limit, maxFeedEntries)
+
+	updated, err := sqlClient.GetNewestPartTime(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to query parts",
+			"details": err.Error(),
+		})
+		return nil, time.Time{}, false
+	}
+
+	// Last-Modified has a resolution of seconds, like created_at
+	if !updated.IsZero() {
+		c.Header("Last-Modified", updated.UTC().Format(http.TimeFormat))
+		if since, err := http.ParseTime(c.GetHeader("If-Modified-Since")); err == nil && !updated.Truncate(time.Second).After(since) {
+			c.Status(http.StatusNotModified)
+			return nil, time.Time{}, false
+		}
+	}
+
+	parts, err := sqlClient.GetFilteredParts(limit, 0, filter, "", false)
+	if err != nil {
+		log.Printf("[GET /api/feeds] ERROR: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to query parts",
+			"details": err.Error(),
+		})
+		return nil, time.Time{}, false
+	}
+
+	sites, err := sqlClient.GetAllSites()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to query sites",
+			"details": err.Error(),
+		})
+		return nil, time.Time{}, false
+	}
+	siteNames := make(map[int]string, len(sites))
+	for _, site := range sites {
+		siteNames[site.ID] = site.Name
+	}
+
+	baseURL := requestBaseURL(c)
+	entries := make([]feedEntry, 0, len(parts))
+	for _, part := range parts {
+		entry := feedEntry{
+			part:  part,
+			id:    fmt.Sprintf("%s/api/parts/%d", baseURL, part.ID),
+			title: part.Name,
+			site:  siteNames[part.SiteID],
+		}
+		if entry.site == "" {
+			entry.site = fmt.Sprintf("Site %d", part.SiteID)
+		}
+		if part.Price != "" {
+			entry.title += " - " + part.Price
+		}
+		if part.ImageURL != "" {
+			entry.thumbnail = baseURL + part.ImageURL
+		}
+		entry.content = feedEntryContent(entry)
+		entries = append(entries, entry)
+	}
+
+	return entries, updated, true
+}
+
+// feedEntryContent returns the HTML content of a feed entry
+func feedEntryContent(entry feedEntry) string {
+	var content strings.Builder
+	if entry.thumbnail != "" {
+		fmt.Fprintf(&content, `<p><img src="%s" alt=""></p>`, html.EscapeString(entry.thumbnail))
+	}
+	content.WriteString("<p>")
+	if entry.part.Price != "" {
+		fmt.Fprintf(&content, "Price: %s<br>", html.EscapeString(entry.part.Price))
+	}
+	fmt.Fprintf(&content, "Site: %s</p>", html.EscapeString(entry.site))
+	fmt.Fprintf(&content, `<p><a href="%s">View listing</a></p>`, html.EscapeString(entry.part.URL))
+	return content.String()
+}
+
+// feedTitleFor returns the title of a feed, naming the search term if there is one
+func feedTitleFor(c *gin.Context) string {
+	if search := strings.TrimSpace(c.Query("search")); search != "" {
+		return fmt.Sprintf("%s: %s", feedTitle, search)
+	}
+	return feedTitle
+}
+
+// requestBaseURL returns the scheme and host the request was sent to, so feeds can
+// link to the API with absolute URLs
+func requestBaseURL(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := c.GetHeader("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + c.Request.Host
+}
+
+// renderFeed writes a feed as XML
+func renderFeed(c *gin.Context, contentType string, feed interface{}) {
+	data, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to render feed",
+			"details": err.Error(),
+		})
+		return
+	}
+	c.Data(http.StatusOK, contentType, append([]byte(xml.Header), data...))
+}
@@ -0,0 +1,95 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"strconv"
+
+	"dsmpartsfinder-api/images"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ImageStore interface {
+	OpenSize(hash string, size int) (*os.File, error)
+}
+
+// registerImageRoutes registers the endpoint serving images from the image store.
+// Images are addressed by the hash of their content and never change, so they
+// can be cached by browsers forever.
+func registerImageRoutes(api *gin.RouterGroup, imageStore ImageStore) {
+	// GET /api/images/:hash?size=160 - Get an image by content hash, optionally as thumbnail
+	api.GET("/images/:hash", func(c *gin.Context) {
+		hash := c.Param("hash")
+
+		size := images.SizeOriginal
+		if sizeStr := c.Query("size"); sizeStr != "" && sizeStr != "original" {
+			parsedSize, err := strconv.Atoi(sizeStr)
+			if err != nil || !images.ValidSize(parsedSize) {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error":   "Invalid image size",
+					"details": fmt.Sprintf("size must be one of %v or 'original'", images.ThumbnailSizes),
+				})
+				return
+			}
+			size = parsedSize
+		}
+
+		file, err := imageStore.OpenSize(hash, size)
+		if errors.Is(err, fs.ErrNotExist) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Image not found",
+			})
+			return
+		} else if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid image hash",
+				"details": err.Error(),
+			})
+			return
+		}
+		defer file.Close()
+
+		info, err := file.Stat()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to read image",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		// The type comes from the image format, never sniffed by the browser, so no
+		// downloaded content is served as a page of the API's origin
+		head := make([]byte, 512)
+		n, err := io.ReadFull(file, head)
+		if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to read image",
+				"details": err.Error(),
+			})
+			return
+		}
+		contentType, err := images.ContentType(head[:n])
+		if err != nil {
+			contentType = "application/octet-stream"
+		}
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to read image",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		c.Header("Content-Type", contentType)
+		c.Header("X-Content-Type-Options", "nosniff")
+		c.Header("Cache-Control", "public, max-age=31536000, immutable")
+		c.Header("ETag", fmt.Sprintf(`"%s-%d"`, hash, size))
+		http.ServeContent(c.Writer, c.Request, "", info.ModTime(), file)
+	})
+}
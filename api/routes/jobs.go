@@ -0,0 +1,94 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"dsmpartsfinder-api/jobs"
+
+	"github.com/gin-gonic/gin"
+)
+
+type JobManager interface {
+	Get(id int) (jobs.Info, error)
+	List() []jobs.Info
+	Cancel(id int) (jobs.Info, error)
+}
+
+// registerJobRoutes registers the endpoints reporting and cancelling fetch jobs
+func registerJobRoutes(api *gin.RouterGroup, jobManager JobManager) {
+	// GET /api/jobs - List recent jobs, newest first
+	api.GET("/jobs", func(c *gin.Context) {
+		infos := jobManager.List()
+		c.JSON(http.StatusOK, gin.H{
+			"data":    infos,
+			"message": "Jobs retrieved successfully",
+			"total":   len(infos),
+		})
+	})
+
+	// GET /api/jobs/:id - Get the status and per-site progress of a job
+	api.GET("/jobs/:id", func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid job ID",
+			})
+			return
+		}
+
+		info, err := jobManager.Get(id)
+		if errors.Is(err, jobs.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Job not found",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"data":    info,
+			"message": "Job retrieved successfully",
+		})
+	})
+
+	// DELETE /api/jobs/:id - Cancel a running job
+	api.DELETE("/jobs/:id", func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid job ID",
+			})
+			return
+		}
+
+		info, err := jobManager.Cancel(id)
+		if errors.Is(err, jobs.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Job not found",
+			})
+			return
+		} else if errors.Is(err, jobs.ErrFinished) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":   "Job already finished",
+				"details": fmt.Sprintf("job %d finished as %s", id, info.Status),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"data":    info,
+			"message": "Job cancelled",
+		})
+	})
+}
+
+// respondJobStarted answers a request that started a job with 202 and its location
+func respondJobStarted(c *gin.Context, info jobs.Info) {
+	c.Header("Location", fmt.Sprintf("/api/jobs/%d", info.ID))
+	c.JSON(http.StatusAccepted, gin.H{
+		"data":    info,
+		"message": "Fetch job started",
+	})
+}
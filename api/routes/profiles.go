@@ -0,0 +1,157 @@
+package routes
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	. "dsmpartsfinder-api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// registerProfileRoutes registers the CRUD endpoints for search profiles
+func registerProfileRoutes(api *gin.RouterGroup, sqlClient SQLClient) {
+	// GET /api/profiles - Get all search profiles
+	api.GET("/profiles", func(c *gin.Context) {
+		profiles, err := sqlClient.GetAllProfiles(c.Query("enabled") == "true")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to query search profiles",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"data":    profiles,
+			"message": "Search profiles retrieved successfully",
+			"total":   len(profiles),
+		})
+	})
+
+	// GET /api/profiles/:id - Get a single search profile by ID
+	api.GET("/profiles/:id", func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid profile ID",
+			})
+			return
+		}
+
+		profile, err := sqlClient.GetProfileByID(id)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Search profile not found",
+			})
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to query search profile",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"data":    profile,
+			"message": "Search profile retrieved successfully",
+		})
+	})
+
+	// POST /api/profiles - Create a search profile
+	api.POST("/profiles", func(c *gin.Context) {
+		var req SearchProfileRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid request body",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		profile, err := sqlClient.CreateProfile(req.ToProfile())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to create search profile",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{
+			"data":    profile,
+			"message": "Search profile created successfully",
+		})
+	})
+
+	// PUT /api/profiles/:id - Update a search profile
+	api.PUT("/profiles/:id", func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid profile ID",
+			})
+			return
+		}
+
+		var req SearchProfileRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid request body",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		profile, err := sqlClient.UpdateProfile(id, req.ToProfile())
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Search profile not found",
+			})
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to update search profile",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"data":    profile,
+			"message": "Search profile updated successfully",
+		})
+	})
+
+	// DELETE /api/profiles/:id - Delete a search profile
+	api.DELETE("/profiles/:id", func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid profile ID",
+			})
+			return
+		}
+
+		err = sqlClient.DeleteProfile(id)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Search profile not found",
+			})
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to delete search profile",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message":    "Search profile deleted successfully",
+			"profile_id": id,
+		})
+	})
+}
@@ -0,0 +1,84 @@
+package routes
+
+import (
+	"io"
+	"net/http"
+	"strings"
+
+	"dsmpartsfinder-api/currency"
+
+	"github.com/gin-gonic/gin"
+)
+
+// registerExchangeRateRoutes registers the endpoints for the exchange rates prices
+// are converted to the display currency with
+func registerExchangeRateRoutes(api *gin.RouterGroup, sqlClient SQLClient) {
+	// GET /api/exchange-rates - Get all exchange rates and the display currency
+	api.GET("/exchange-rates", func(c *gin.Context) {
+		rates, err := sqlClient.GetExchangeRates()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to query exchange rates",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"data":             rates,
+			"display_currency": sqlClient.DisplayCurrency(),
+			"message":          "Exchange rates retrieved successfully",
+			"total":            len(rates),
+		})
+	})
+
+	// PUT /api/exchange-rates - Replace all exchange rates with a JSON or CSV (Content-Type: text/csv) body
+	api.PUT("/exchange-rates", func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Failed to read request body",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		var rates currency.Rates
+		if strings.Contains(c.ContentType(), "csv") {
+			rates, err = currency.ParseCSV(body)
+		} else {
+			rates, err = currency.ParseJSON(body)
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid exchange rates",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		if err := sqlClient.ReplaceExchangeRates(rates); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to store exchange rates",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		stored, err := sqlClient.GetExchangeRates()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to query exchange rates",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"data":             stored,
+			"display_currency": sqlClient.DisplayCurrency(),
+			"message":          "Exchange rates replaced successfully",
+			"total":            len(stored),
+		})
+	})
+}
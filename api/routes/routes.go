@@ -3,11 +3,14 @@ package routes
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"log"
 	"net/http"
+	"slices"
 	"strconv"
 	"time"
 
+	"dsmpartsfinder-api/jobs"
 	. "dsmpartsfinder-api/models"
 	"dsmpartsfinder-api/siteclients"
 
@@ -17,30 +20,59 @@ import (
 type SQLClient interface {
 	GetAllSites() ([]Site, error)
 	GetSiteByID(id int) (*Site, error)
-	CreateSite(name, url string) (*Site, error)
-	UpdateSite(id int, name, url string) (*Site, error)
+	CreateSite(name, url, clientType, clientConfig string) (*Site, error)
+	UpdateSite(id int, name, url, clientType, clientConfig string) (*Site, error)
+	UpdateSiteSchedule(id int, schedule string, jitterSeconds int, quietHoursStart, quietHoursEnd *int) (*Site, error)
 	DeleteSite(id int) error
 
 	GetAllParts(limit, offset int) ([]Part, error)
 	GetPartByID(id int) (*Part, error)
 	GetPartsBySiteID(siteID, limit, offset int) ([]Part, error)
 	DeletePartsBySiteID(siteID int) error
-	GetFilteredParts(limit, offset int, typeFilter string, siteIDs []int, newerThan time.Time, search string, sortBy string, sortDesc bool) ([]Part, error)
+	GetFilteredParts(limit, offset int, filter PartsFilter, sortBy string, sortDesc bool) ([]Part, error)
+	GetNewestPartTime(filter PartsFilter) (time.Time, error)
+	GetPriceHistory(partID int) ([]PriceChange, error)
+	SetPartType(id int, typeName string) (*Part, error)
+	ReclassifyParts(ctx context.Context) (int, error)
+
+	GetAllProfiles(enabledOnly bool) ([]SearchProfile, error)
+	GetProfileByID(id int) (*SearchProfile, error)
+	CreateProfile(profile SearchProfile) (*SearchProfile, error)
+	UpdateProfile(id int, profile SearchProfile) (*SearchProfile, error)
+	DeleteProfile(id int) error
+
+	GetAllSavedSearches(enabledOnly bool) ([]SavedSearch, error)
+	GetSavedSearchByID(id int) (*SavedSearch, error)
+	CreateSavedSearch(search SavedSearch) (*SavedSearch, error)
+	UpdateSavedSearch(id int, search SavedSearch) (*SavedSearch, error)
+	DeleteSavedSearch(id int) error
+
+	GetAlerts(limit, offset int, filter AlertsFilter) ([]Alert, error)
+	GetAlertsCount(filter AlertsFilter) (int, error)
+	SetAlertRead(id int, read bool) error
+	MarkAlertsRead(filter AlertsFilter) (int64, error)
+
+	GetFetchRuns(limit, offset int, filter FetchRunsFilter) ([]FetchRun, error)
+	GetFetchRunsCount(filter FetchRunsFilter) (int, error)
+
+	GetExchangeRates() ([]ExchangeRate, error)
+	ReplaceExchangeRates(rates map[string]float64) error
+	DisplayCurrency() string
 }
 
 type PartsService interface {
-	FetchAndStoreParts(ctx context.Context, siteID int, params siteclients.SearchParams) ([]Part, error)
+	StartFetchJob(trigger string, siteIDs []int, params siteclients.SearchParams) *jobs.Job
 	GetRegisteredSiteIDs() []int
 	GetAllParts(limit, offset int) ([]Part, error)
-	GetFilteredParts(limit, offset int, typeFilter string, siteIDs []int, newerThan time.Time, search string, sortBy string, sortDesc bool) ([]Part, error)
+	GetFilteredParts(limit, offset int, filter PartsFilter, sortBy string, sortDesc bool) ([]Part, error)
 	GetPartByID(id int) (*Part, error)
 	GetPartsBySiteID(siteID, limit, offset int) ([]Part, error)
 	DeletePartsBySiteID(siteID int) error
 	GetTotalPartsCount() (int, error)
-	GetFilteredPartsCount(typeFilter string, siteIDs []int, newerThan time.Time, search string) (int, error)
+	GetFilteredPartsCount(filter PartsFilter) (int, error)
 }
 
-func RegisterAPIRoutes(r *gin.Engine, sqlClient SQLClient, partsService PartsService) {
+func RegisterAPIRoutes(r *gin.Engine, sqlClient SQLClient, partsService PartsService, imageStore ImageStore, eventBus EventBus, jobManager JobManager, scheduler ScheduleManager) {
 	api := r.Group("/api")
 	{
 		// Health check endpoint
@@ -101,198 +133,138 @@ func RegisterAPIRoutes(r *gin.Engine, sqlClient SQLClient, partsService PartsSer
 			})
 		})
 
-		if gin.Mode() != gin.ReleaseMode {
-			// POST /api/parts/fetch - Fetch parts from all sites
-			api.POST("/parts/fetch", func(c *gin.Context) {
-				log.Println("[POST /api/parts/fetch] Endpoint called")
-
-				var req FetchPartsRequest
-				if err := c.ShouldBindJSON(&req); err != nil {
-					log.Printf("[POST /api/parts/fetch] ERROR: Invalid request body: %v", err)
-					c.JSON(http.StatusBadRequest, gin.H{
-						"error":   "Invalid request body",
-						"details": err.Error(),
-					})
-					return
-				}
-
-				log.Printf("[POST /api/parts/fetch] Request: SiteID=%d, Limit=%d", req.SiteID, req.Limit)
-
-				// Convert to search params
-				params := siteclients.SearchParams{}
-
-				// Fetch and store parts
-				parts, err := partsService.FetchAndStoreParts(c.Request.Context(), req.SiteID, params)
-				if err != nil {
-					log.Printf("[POST /api/parts/fetch] ERROR: %v", err)
-					c.JSON(http.StatusInternalServerError, gin.H{
-						"error":   "Failed to fetch and store parts",
-						"details": err.Error(),
-					})
-					return
-				}
-
-				log.Printf("[POST /api/parts/fetch] Successfully fetched and stored %d parts", len(parts))
-
-				c.JSON(http.StatusOK, gin.H{
-					"data":    parts,
-					"message": "Parts fetched and stored successfully",
-					"total":   len(parts),
+		registerProfileRoutes(api, sqlClient)
+		registerImageRoutes(api, imageStore)
+		registerExchangeRateRoutes(api, sqlClient)
+		registerCategoryRoutes(api, sqlClient)
+		registerSavedSearchRoutes(api, sqlClient)
+		registerAlertRoutes(api, sqlClient)
+		registerFeedRoutes(api, sqlClient)
+		registerEventRoutes(api, eventBus)
+		registerJobRoutes(api, jobManager)
+		registerScheduleRoutes(api, sqlClient, partsService, scheduler)
+		registerRunRoutes(api, sqlClient)
+
+		// POST /api/parts/fetch - Fetch parts from one site in a background job
+		api.POST("/parts/fetch", func(c *gin.Context) {
+			log.Println("[POST /api/parts/fetch] Endpoint called")
+
+			var req FetchPartsRequest
+			if err := c.ShouldBindJSON(&req); err != nil {
+				log.Printf("[POST /api/parts/fetch] ERROR: Invalid request body: %v", err)
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error":   "Invalid request body",
+					"details": err.Error(),
 				})
-			})
-		}
-
-		if gin.Mode() != gin.ReleaseMode {
-			// POST /api/parts/fetch-all - Fetch parts from all registered sites
-			api.POST("/parts/fetch-all", func(c *gin.Context) {
-				log.Println("[POST /api/parts/fetch-all] Endpoint called")
-
-				var req struct {
-					VehicleType string `json:"vehicle_type"`
-					Make        string `json:"make"`
-					BaseModel   string `json:"base_model"`
-					Model       string `json:"model"`
-					YearFrom    int    `json:"year_from"`
-					YearTo      int    `json:"year_to"`
-					Offset      int    `json:"offset"`
-					Limit       int    `json:"limit"`
-				}
-
-				if err := c.ShouldBindJSON(&req); err != nil {
-					// If no body provided, use defaults
-					log.Printf("[POST /api/parts/fetch-all] No valid JSON body, using defaults. Error: %v", err)
-					req.YearFrom = 1960
-					req.YearTo = 2025
-					req.Limit = 30
-				}
+				return
+			}
 
-				// Set defaults if not provided
-				if req.YearFrom == 0 {
-					req.YearFrom = 1960
-				}
-				if req.YearTo == 0 {
-					req.YearTo = 2025
-				}
-				if req.Limit == 0 {
-					req.Limit = 30
-				}
+			log.Printf("[POST /api/parts/fetch] Request: SiteID=%d, Limit=%d", req.SiteID, req.Limit)
 
-				log.Printf("[POST /api/parts/fetch-all] Request params: YearFrom=%d, YearTo=%d, Limit=%d, Make=%s, Model=%s",
-					req.YearFrom, req.YearTo, req.Limit, req.Make, req.Model)
-
-				// Convert to search params
-				params := siteclients.SearchParams{
-					VehicleType: req.VehicleType,
-					Make:        req.Make,
-					BaseModel:   req.BaseModel,
-					Model:       req.Model,
-					YearFrom:    req.YearFrom,
-					YearTo:      req.YearTo,
-					Offset:      req.Offset,
-					Limit:       req.Limit,
-				}
+			if !slices.Contains(partsService.GetRegisteredSiteIDs(), req.SiteID) {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error": fmt.Sprintf("No site client registered for site ID %d", req.SiteID),
+				})
+				return
+			}
 
-				// Get all registered site IDs
-				siteIDs := partsService.GetRegisteredSiteIDs()
-				log.Printf("[POST /api/parts/fetch-all] Found %d registered site(s): %v", len(siteIDs), siteIDs)
+			// Convert to search params
+			params := siteclients.SearchParams{
+				VehicleType: req.VehicleType,
+				Make:        req.Make,
+				BaseModel:   req.BaseModel,
+				Model:       req.Model,
+				YearFrom:    req.YearFrom,
+				YearTo:      req.YearTo,
+				Offset:      req.Offset,
+				Limit:       req.Limit,
+			}
 
-				if len(siteIDs) == 0 {
-					log.Println("[POST /api/parts/fetch-all] ERROR: No site clients registered")
-					c.JSON(http.StatusBadRequest, gin.H{
-						"error": "No site clients registered",
-					})
-					return
-				}
+			job := partsService.StartFetchJob(jobs.TriggerAPI, []int{req.SiteID}, params)
+			log.Printf("[POST /api/parts/fetch] Started job %d", job.ID())
+			respondJobStarted(c, job.Info())
+		})
 
-				// Create a context with timeout
-				ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Minute)
-				defer cancel()
+		// POST /api/parts/fetch-all - Fetch parts from all registered sites in a background job
+		api.POST("/parts/fetch-all", func(c *gin.Context) {
+			log.Println("[POST /api/parts/fetch-all] Endpoint called")
+
+			var req struct {
+				VehicleType string `json:"vehicle_type"`
+				Make        string `json:"make"`
+				BaseModel   string `json:"base_model"`
+				Model       string `json:"model"`
+				YearFrom    int    `json:"year_from"`
+				YearTo      int    `json:"year_to"`
+				Offset      int    `json:"offset"`
+				Limit       int    `json:"limit"`
+			}
 
-				// Channel to collect results from goroutines
-				type FetchResult struct {
-					siteID int
-					parts  []Part
-					err    error
-				}
-				results := make(chan FetchResult, len(siteIDs))
-
-				// Launch a goroutine for each site
-				log.Println("[POST /api/parts/fetch-all] Starting concurrent fetch from all sites...")
-				for _, siteID := range siteIDs {
-					go func(id int) {
-						log.Printf("[POST /api/parts/fetch-all] Starting fetch for site ID: %d", id)
-						parts, err := partsService.FetchAndStoreParts(ctx, id, params)
-						results <- FetchResult{
-							siteID: id,
-							parts:  parts,
-							err:    err,
-						}
-					}(siteID)
-				}
+			if err := c.ShouldBindJSON(&req); err != nil {
+				// If no body provided, use defaults
+				log.Printf("[POST /api/parts/fetch-all] No valid JSON body, using defaults. Error: %v", err)
+				req.YearFrom = 1960
+				req.YearTo = 2025
+				req.Limit = 30
+			}
 
-				// Collect results
-				allParts := make([]Part, 0)
-				errors := make(map[int]string)
-
-				// Wait for all fetches to complete
-				for range siteIDs {
-					result := <-results
-					if result.err != nil {
-						log.Printf("[POST /api/parts/fetch-all] ERROR fetching parts from site %d: %v", result.siteID, result.err)
-						errors[result.siteID] = result.err.Error()
-						continue
-					}
-					log.Printf("[POST /api/parts/fetch-all] Got %d parts from site %d", len(result.parts), result.siteID)
-					allParts = append(allParts, result.parts...)
-				}
+			// Set defaults if not provided
+			if req.YearFrom == 0 {
+				req.YearFrom = 1960
+			}
+			if req.YearTo == 0 {
+				req.YearTo = 2025
+			}
+			if req.Limit == 0 {
+				req.Limit = 30
+			}
 
-				log.Printf("[POST /api/parts/fetch-all] Total parts collected: %d", len(allParts))
+			log.Printf("[POST /api/parts/fetch-all] Request params: YearFrom=%d, YearTo=%d, Limit=%d, Make=%s, Model=%s",
+				req.YearFrom, req.YearTo, req.Limit, req.Make, req.Model)
+
+			// Convert to search params
+			params := siteclients.SearchParams{
+				VehicleType: req.VehicleType,
+				Make:        req.Make,
+				BaseModel:   req.BaseModel,
+				Model:       req.Model,
+				YearFrom:    req.YearFrom,
+				YearTo:      req.YearTo,
+				Offset:      req.Offset,
+				Limit:       req.Limit,
+			}
 
-				response := gin.H{
-					"data":    allParts,
-					"total":   len(allParts),
-					"sites":   len(siteIDs),
-					"message": "Parts fetched from all sites",
-				}
+			// Get all registered site IDs
+			siteIDs := partsService.GetRegisteredSiteIDs()
+			log.Printf("[POST /api/parts/fetch-all] Found %d registered site(s): %v", len(siteIDs), siteIDs)
 
-				if len(errors) > 0 {
-					log.Printf("[POST /api/parts/fetch-all] Encountered errors for %d sites: %v", len(errors), errors)
-					response["errors"] = errors
-				}
+			if len(siteIDs) == 0 {
+				log.Println("[POST /api/parts/fetch-all] ERROR: No site clients registered")
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error": "No site clients registered",
+				})
+				return
+			}
 
-				log.Printf("[POST /api/parts/fetch-all] Returning response with %d parts", len(allParts))
-				c.JSON(http.StatusOK, response)
-			})
-		}
+			job := partsService.StartFetchJob(jobs.TriggerAPI, siteIDs, params)
+			log.Printf("[POST /api/parts/fetch-all] Started job %d", job.ID())
+			respondJobStarted(c, job.Info())
+		})
 
 		// GET /api/parts - Get all parts with pagination
 		api.GET("/parts", func(c *gin.Context) {
 			limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
 			offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
-			typeFilter := c.Query("type")
-			siteIDsStr := c.QueryArray("site_ids[]")
-			siteIDs := make([]int, 0)
-			for _, idStr := range siteIDsStr {
-				if id, err := strconv.Atoi(idStr); err == nil {
-					siteIDs = append(siteIDs, id)
-				}
-			}
+			filter := ParsePartsFilter(c.Request.URL.Query())
 			sortBy := c.DefaultQuery("sort", "")
 			sortDesc := c.DefaultQuery("sort_desc", "false") == "true"
 
 			// If any filter is specified, use filtered endpoint
-			search := c.Query("search")
-			if typeFilter != "" || len(siteIDs) > 0 || c.Query("newer_than_hours") != "" || search != "" || sortBy != "" {
-				var newerThan time.Time
-				if c.Query("newer_than_hours") != "" {
-					hours, _ := strconv.Atoi(c.DefaultQuery("newer_than_hours", "72"))
-					newerThan = time.Now().Add(-time.Duration(hours) * time.Hour)
-				}
+			if !filter.IsEmpty() || sortBy != "" {
+				log.Printf("[GET /api/parts] Called with filters: limit=%d, offset=%d, filter=%+v",
+					limit, offset, filter)
 
-				log.Printf("[GET /api/parts] Called with filters: limit=%d, offset=%d, type=%s, site_id=%d, newer_than=%v",
-					limit, offset, typeFilter, siteIDs, newerThan)
-
-				parts, err := partsService.GetFilteredParts(limit, offset, typeFilter, siteIDs, newerThan, search, sortBy, sortDesc)
+				parts, err := partsService.GetFilteredParts(limit, offset, filter, sortBy, sortDesc)
 				if err != nil {
 					log.Printf("[GET /api/parts] ERROR: %v", err)
 					c.JSON(http.StatusInternalServerError, gin.H{
@@ -302,7 +274,7 @@ func RegisterAPIRoutes(r *gin.Engine, sqlClient SQLClient, partsService PartsSer
 					return
 				}
 
-				total, err := partsService.GetFilteredPartsCount(typeFilter, siteIDs, newerThan, search)
+				total, err := partsService.GetFilteredPartsCount(filter)
 				if err != nil {
 					log.Printf("[GET /api/parts] ERROR getting filtered count: %v", err)
 					c.JSON(http.StatusInternalServerError, gin.H{
@@ -387,6 +359,48 @@ func RegisterAPIRoutes(r *gin.Engine, sqlClient SQLClient, partsService PartsSer
 			})
 		})
 
+		// GET /api/parts/:id/history - Get the price changes of a part
+		api.GET("/parts/:id/history", func(c *gin.Context) {
+			id, err := strconv.Atoi(c.Param("id"))
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error": "Invalid part ID",
+				})
+				return
+			}
+
+			part, err := partsService.GetPartByID(id)
+			if err == sql.ErrNoRows {
+				c.JSON(http.StatusNotFound, gin.H{
+					"error": "Part not found",
+				})
+				return
+			} else if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error":   "Failed to query part",
+					"details": err.Error(),
+				})
+				return
+			}
+
+			history, err := sqlClient.GetPriceHistory(id)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error":   "Failed to query price history",
+					"details": err.Error(),
+				})
+				return
+			}
+
+			c.JSON(http.StatusOK, gin.H{
+				"data":          history,
+				"message":       "Price history retrieved successfully",
+				"total":         len(history),
+				"part_id":       id,
+				"current_price": part.Price,
+			})
+		})
+
 		// GET /api/sites/:id/parts - Get all parts for a specific site
 		api.GET("/sites/:id/parts", func(c *gin.Context) {
 			siteID, err := strconv.Atoi(c.Param("id"))
@@ -0,0 +1,93 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	. "dsmpartsfinder-api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// registerRunRoutes registers the endpoint listing the recorded fetch runs
+func registerRunRoutes(api *gin.RouterGroup, sqlClient SQLClient) {
+	// GET /api/runs?site_id=2&trigger=scheduler&max_fetched=0 - Get fetch runs, newest first
+	api.GET("/runs", func(c *gin.Context) {
+		limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+		offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+		filter, err := parseFetchRunsFilter(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid filter",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		runs, err := sqlClient.GetFetchRuns(limit, offset, filter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to query fetch runs",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		total, err := sqlClient.GetFetchRunsCount(filter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to count fetch runs",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"data":    runs,
+			"message": "Fetch runs retrieved successfully",
+			"total":   total,
+			"limit":   limit,
+			"offset":  offset,
+		})
+	})
+}
+
+// parseFetchRunsFilter reads the fetch runs filter from the query parameters;
+// since and until are RFC 3339 times or dates
+func parseFetchRunsFilter(c *gin.Context) (FetchRunsFilter, error) {
+	filter := FetchRunsFilter{
+		Trigger: c.Query("trigger"),
+		Status:  c.Query("status"),
+	}
+	filter.SiteID, _ = strconv.Atoi(c.Query("site_id"))
+
+	var err error
+	if filter.Since, err = parseTimeParam(c.Query("since")); err != nil {
+		return filter, fmt.Errorf("invalid since: %w", err)
+	}
+	if filter.Until, err = parseTimeParam(c.Query("until")); err != nil {
+		return filter, fmt.Errorf("invalid until: %w", err)
+	}
+
+	if maxFetched := c.Query("max_fetched"); maxFetched != "" {
+		value, err := strconv.Atoi(maxFetched)
+		if err != nil {
+			return filter, fmt.Errorf("invalid max_fetched: %w", err)
+		}
+		filter.MaxFetched = &value
+	}
+	return filter, nil
+}
+
+// parseTimeParam parses an RFC 3339 time or a date, the zero time when empty
+func parseTimeParam(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, value)
+}
@@ -0,0 +1,136 @@
+package routes
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	. "dsmpartsfinder-api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ScheduleManager interface {
+	ApplySchedule(site Site) error
+	ScheduledRuns(siteID int) (next, prev *time.Time)
+}
+
+// registerScheduleRoutes registers the endpoints reporting and changing when the
+// scheduler fetches every site
+func registerScheduleRoutes(api *gin.RouterGroup, sqlClient SQLClient, partsService PartsService, scheduler ScheduleManager) {
+	// GET /api/schedule - Get the schedule of every site with its next and previous run
+	api.GET("/schedule", func(c *gin.Context) {
+		schedules, err := siteSchedules(sqlClient, partsService, scheduler)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to query schedules",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"data":    schedules,
+			"message": "Schedules retrieved successfully",
+			"total":   len(schedules),
+		})
+	})
+
+	// PUT /api/schedule - Replace the schedules of the listed sites, applied right away
+	api.PUT("/schedule", func(c *gin.Context) {
+		var reqs []UpdateScheduleRequest
+		if err := c.ShouldBindJSON(&reqs); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid request body",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		// Check every schedule before storing any of them
+		for _, req := range reqs {
+			if err := req.Validate(); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error":   fmt.Sprintf("Invalid schedule for site ID %d", req.SiteID),
+					"details": err.Error(),
+				})
+				return
+			}
+
+			_, err := sqlClient.GetSiteByID(req.SiteID)
+			if err == sql.ErrNoRows {
+				c.JSON(http.StatusNotFound, gin.H{
+					"error": fmt.Sprintf("Site with ID %d not found", req.SiteID),
+				})
+				return
+			} else if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error":   "Failed to query site",
+					"details": err.Error(),
+				})
+				return
+			}
+		}
+
+		for _, req := range reqs {
+			site, err := sqlClient.UpdateSiteSchedule(req.SiteID, req.Schedule, req.JitterSeconds, req.QuietHoursStart, req.QuietHoursEnd)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error":   "Failed to update schedule",
+					"details": err.Error(),
+				})
+				return
+			}
+			if err := scheduler.ApplySchedule(*site); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error":   "Failed to apply schedule",
+					"details": err.Error(),
+				})
+				return
+			}
+		}
+
+		schedules, err := siteSchedules(sqlClient, partsService, scheduler)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to query schedules",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"data":    schedules,
+			"message": "Schedules updated successfully",
+			"total":   len(schedules),
+		})
+	})
+}
+
+// siteSchedules returns the schedules of all sites with their runs
+func siteSchedules(sqlClient SQLClient, partsService PartsService, scheduler ScheduleManager) ([]SiteSchedule, error) {
+	sites, err := sqlClient.GetAllSites()
+	if err != nil {
+		return nil, err
+	}
+
+	registered := partsService.GetRegisteredSiteIDs()
+	schedules := make([]SiteSchedule, len(sites))
+	for i, site := range sites {
+		next, prev := scheduler.ScheduledRuns(site.ID)
+		schedules[i] = SiteSchedule{
+			SiteID:          site.ID,
+			SiteName:        site.Name,
+			Schedule:        site.Schedule,
+			JitterSeconds:   site.ScheduleJitterSeconds,
+			QuietHoursStart: site.QuietHoursStart,
+			QuietHoursEnd:   site.QuietHoursEnd,
+			Registered:      slices.Contains(registered, site.ID),
+			NextRun:         next,
+			PrevRun:         prev,
+		}
+	}
+	return schedules, nil
+}
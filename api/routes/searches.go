@@ -0,0 +1,172 @@
+package routes
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+
+	. "dsmpartsfinder-api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// registerSavedSearchRoutes registers the CRUD endpoints for saved searches
+func registerSavedSearchRoutes(api *gin.RouterGroup, sqlClient SQLClient) {
+	// GET /api/saved-searches - Get all saved searches
+	api.GET("/saved-searches", func(c *gin.Context) {
+		searches, err := sqlClient.GetAllSavedSearches(c.Query("enabled") == "true")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to query saved searches",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"data":    searches,
+			"message": "Saved searches retrieved successfully",
+			"total":   len(searches),
+		})
+	})
+
+	// GET /api/saved-searches/:id - Get a single saved search by ID
+	api.GET("/saved-searches/:id", func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid saved search ID",
+			})
+			return
+		}
+
+		search, err := sqlClient.GetSavedSearchByID(id)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Saved search not found",
+			})
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to query saved search",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"data":    search,
+			"message": "Saved search retrieved successfully",
+		})
+	})
+
+	// POST /api/saved-searches - Create a saved search
+	api.POST("/saved-searches", func(c *gin.Context) {
+		search, ok := bindSavedSearch(c)
+		if !ok {
+			return
+		}
+
+		created, err := sqlClient.CreateSavedSearch(search)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to create saved search",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{
+			"data":    created,
+			"message": "Saved search created successfully",
+		})
+	})
+
+	// PUT /api/saved-searches/:id - Update a saved search
+	api.PUT("/saved-searches/:id", func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid saved search ID",
+			})
+			return
+		}
+
+		search, ok := bindSavedSearch(c)
+		if !ok {
+			return
+		}
+
+		updated, err := sqlClient.UpdateSavedSearch(id, search)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Saved search not found",
+			})
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to update saved search",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"data":    updated,
+			"message": "Saved search updated successfully",
+		})
+	})
+
+	// DELETE /api/saved-searches/:id - Delete a saved search
+	api.DELETE("/saved-searches/:id", func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid saved search ID",
+			})
+			return
+		}
+
+		err = sqlClient.DeleteSavedSearch(id)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Saved search not found",
+			})
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to delete saved search",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message":         "Saved search deleted successfully",
+			"saved_search_id": id,
+		})
+	})
+}
+
+// bindSavedSearch reads a saved search from the request body, responding with an
+// error and returning false when it is invalid
+func bindSavedSearch(c *gin.Context) (SavedSearch, bool) {
+	var req SavedSearchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+		return SavedSearch{}, false
+	}
+
+	search, err := req.ToSavedSearch()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid saved search",
+			"details": err.Error(),
+		})
+		return SavedSearch{}, false
+	}
+	return search, true
+}
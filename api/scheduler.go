@@ -2,46 +2,74 @@ package main
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
 	"log"
+	"math/rand/v2"
+	"strings"
+	"sync"
 	"time"
 
-	"dsmpartsfinder-api/siteclients"
+	"dsmpartsfinder-api/jobs"
+	. "dsmpartsfinder-api/models"
 
 	"github.com/robfig/cron/v3"
 )
 
-// Scheduler handles automatic scheduled tasks
+// Scheduler fetches every site on the schedule stored with it
 type Scheduler struct {
 	cron         *cron.Cron
 	partsService *PartsService
+	jobs         *jobs.Manager
+	stop         chan struct{} // closed when the scheduler stops, ends jitter delays
+
+	mu          sync.Mutex
+	entries     map[int]cron.EntryID // cron entries of the scheduled sites
+	digestSince time.Time            // alerts created since then go in the next digest
 }
 
-// NewScheduler creates a new scheduler instance
-func NewScheduler(partsService *PartsService) *Scheduler {
+// NewScheduler creates a new scheduler instance; its fetches run as jobs of jobManager
+func NewScheduler(partsService *PartsService, jobManager *jobs.Manager) *Scheduler {
 	// Create cron with seconds precision
-	c := cron.New(cron.WithSeconds())
+	c := cron.New(cron.WithParser(ScheduleParser))
 
 	return &Scheduler{
 		cron:         c,
 		partsService: partsService,
+		jobs:         jobManager,
+		stop:         make(chan struct{}),
+		entries:      make(map[int]cron.EntryID),
 	}
 }
 
-// Start begins the scheduled tasks
+// Start fetches the scheduled sites once and then on their schedules
 func (s *Scheduler) Start() error {
 	log.Println("[Scheduler] Setting up scheduled tasks...")
 
-	log.Println("[Scheduler] Starting startup fetch")
-	s.fetchAllParts()
+	s.mu.Lock()
+	s.digestSince = time.Now()
+	s.mu.Unlock()
 
-	_, err := s.cron.AddFunc("0 0 * * * *", func() {
-		log.Println("[Scheduler] Running scheduled hourly fetch...")
-		s.fetchAllParts()
-	})
+	sites, err := s.partsService.GetRegisteredSites()
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to load sites: %w", err)
+	}
+
+	var startupIDs []int
+	for _, site := range sites {
+		if err := s.ApplySchedule(site); err != nil {
+			log.Printf("[Scheduler] ERROR: Not scheduling site %d: %v", site.ID, err)
+			continue
+		}
+		if site.Schedule != "" && !site.InQuietHours(time.Now()) {
+			startupIDs = append(startupIDs, site.ID)
+		}
 	}
 
+	log.Println("[Scheduler] Starting startup fetch")
+	s.fetchSites(jobs.TriggerStartup, startupIDs)
+
 	// Start the cron scheduler
 	s.cron.Start()
 	log.Println("[Scheduler] Scheduler started successfully")
@@ -52,98 +80,231 @@ func (s *Scheduler) Start() error {
 // Stop stops the scheduler
 func (s *Scheduler) Stop() {
 	log.Println("[Scheduler] Stopping scheduler...")
+	close(s.stop)
 	s.cron.Stop()
 	log.Println("[Scheduler] Scheduler stopped")
 }
 
-// fetchAllParts fetches parts from all registered sites
-func (s *Scheduler) fetchAllParts() {
+// ApplySchedule replaces the cron entry of a site with one for its current
+// schedule, so changes apply without a restart. Sites without a schedule or a
+// registered site client are not scheduled.
+func (s *Scheduler) ApplySchedule(site Site) error {
+	var schedule cron.Schedule
+	if site.Schedule != "" {
+		var err error
+		schedule, err = ScheduleParser.Parse(site.Schedule)
+		if err != nil {
+			return fmt.Errorf("invalid schedule '%s': %w", site.Schedule, err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if id, exists := s.entries[site.ID]; exists {
+		s.cron.Remove(id)
+		delete(s.entries, site.ID)
+	}
+
+	if schedule == nil {
+		log.Printf("[Scheduler] Site %d has no schedule", site.ID)
+		return nil
+	}
+	if _, err := s.partsService.GetSiteClient(site.ID); err != nil {
+		log.Printf("[Scheduler] Not scheduling site %d: %v", site.ID, err)
+		return nil
+	}
+
+	// A site whose fetch still runs when it is due again skips that run
+	job := cron.NewChain(cron.SkipIfStillRunning(cron.PrintfLogger(log.Default()))).
+		Then(cron.FuncJob(func() { s.runScheduledFetch(site) }))
+	s.entries[site.ID] = s.cron.Schedule(schedule, job)
+	log.Printf("[Scheduler] Scheduled site %d at '%s' (jitter %ds)", site.ID, site.Schedule, site.ScheduleJitterSeconds)
+	return nil
+}
+
+// ScheduledRuns returns when the scheduler fetches a site next, before jitter, and
+// when the site was last fetched, by any trigger; nil when unknown
+func (s *Scheduler) ScheduledRuns(siteID int) (next, prev *time.Time) {
+	s.mu.Lock()
+	if id, exists := s.entries[siteID]; exists {
+		entry := s.cron.Entry(id)
+		nextRun := entry.Next
+		// Entries only get their next run once the cron scheduler started
+		if nextRun.IsZero() && entry.Schedule != nil {
+			nextRun = entry.Schedule.Next(time.Now())
+		}
+		if !nextRun.IsZero() {
+			next = &nextRun
+		}
+	}
+	s.mu.Unlock()
+
+	// Fetch runs survive restarts, unlike anything kept by the scheduler
+	if run, err := s.partsService.GetLastFetchRun(siteID); err == nil {
+		prev = &run.StartedAt
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("[Scheduler] WARNING: Could not get last fetch run of site %d: %v", siteID, err)
+	}
+	return next, prev
+}
+
+// runScheduledFetch fetches a site when its schedule is due, after a random delay
+// of up to its jitter, unless that falls in its quiet hours
+func (s *Scheduler) runScheduledFetch(site Site) {
+	if site.ScheduleJitterSeconds > 0 {
+		delay := time.Duration(rand.Int64N(int64(site.ScheduleJitterSeconds) * int64(time.Second)))
+		log.Printf("[Scheduler] Delaying scheduled fetch of site %d by %v", site.ID, delay.Round(time.Second))
+		select {
+		case <-time.After(delay):
+		case <-s.stop:
+			return
+		}
+	}
+
+	if site.InQuietHours(time.Now()) {
+		log.Printf("[Scheduler] Skipping scheduled fetch of site %d in its quiet hours", site.ID)
+		return
+	}
+
+	log.Printf("[Scheduler] Running scheduled fetch of site %d...", site.ID)
+	s.fetchSites(jobs.TriggerScheduler, []int{site.ID})
+}
+
+// fetchSites fetches parts from the given sites in a job and waits for it
+func (s *Scheduler) fetchSites(trigger string, siteIDs []int) {
 	startTime := time.Now()
 	log.Println("[Scheduler] ========================================")
 	log.Println("[Scheduler] Starting automatic parts fetch...")
 	log.Println("[Scheduler] ========================================")
 
-	// Get all registered site IDs
-	siteIDs := s.partsService.GetRegisteredSiteIDs()
 	if len(siteIDs) == 0 {
-		log.Println("[Scheduler] WARNING: No site clients registered")
+		log.Println("[Scheduler] WARNING: No scheduled sites to fetch")
 		return
 	}
 
 	log.Printf("[Scheduler] Fetching from %d site(s): %v", len(siteIDs), siteIDs)
 
-	// Default search parameters - fetch everything
-	params := siteclients.SearchParams{
-		VehicleType: "P",
-		Make:        "Mitsubishi",
-		BaseModel:   "Eclipse",
-		Model:       "",
-		YearFrom:    1989,
-		YearTo:      2000,
-		Offset:      0,
-		Limit:       10000, // High limit to get everything
+	profiles, err := s.partsService.GetEnabledProfiles()
+	if err != nil {
+		log.Printf("[Scheduler] ERROR: Failed to load search profiles: %v", err)
+		return
+	}
+	if len(profiles) == 0 {
+		log.Println("[Scheduler] WARNING: No enabled search profiles")
+		return
 	}
 
-	// Track statistics with channels
+	log.Printf("[Scheduler] Running %d search profile(s)", len(profiles))
+
 	type FetchResult struct {
-		siteID     int
-		partsCount int
-		duration   time.Duration
-		err        error
-	}
-	results := make(chan FetchResult, len(siteIDs))
-
-	// Create a context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-	defer cancel()
-
-	// Launch goroutine for each site
-	for _, siteID := range siteIDs {
-		go func(id int) {
-			siteStartTime := time.Now()
-			parts, err := s.partsService.FetchAndStoreParts(ctx, id, params)
-			results <- FetchResult{
-				siteID:     id,
-				partsCount: len(parts),
-				duration:   time.Since(siteStartTime),
-				err:        err,
-			}
-		}(siteID)
+		siteID       int
+		partsFetched int // listings on the fetched pages
+		partsNew     int // listings stored as new parts
+		duration     time.Duration
+		err          error
 	}
 
-	// Collect results
-	var totalParts, totalNew, totalErrors int
-	for range siteIDs {
-		result := <-results
-		if result.err != nil {
-			log.Printf("[Scheduler] ERROR: Failed to fetch from site %d: %v", result.siteID, result.err)
-			totalErrors++
-			continue
+	var totalFetched, totalNew, totalErrors int
+	job := s.jobs.Start(trigger, siteIDs, func(ctx context.Context, job *jobs.Job) error {
+		ctx, cancel := context.WithTimeout(ctx, fetchJobTimeout)
+		defer cancel()
+
+		results := make(chan FetchResult, len(siteIDs))
+
+		// Launch goroutine for each site, running the profiles one after another
+		for _, siteID := range siteIDs {
+			go func(id int) {
+				siteStartTime := time.Now()
+				result := FetchResult{siteID: id}
+				site := s.partsService.StartSiteFetch(job, id)
+				var failed []string
+				for _, profile := range profiles {
+					// Parts from pages fetched before an error are stored as well
+					_, err := s.partsService.FetchAndStorePartsForProfile(ctx, id, profile, site)
+					if err != nil {
+						log.Printf("[Scheduler] ERROR: Profile '%s' failed for site %d: %v", profile.Name, id, err)
+						failed = append(failed, profile.Name)
+						site.AddError(fmt.Errorf("profile '%s': %w", profile.Name, err))
+						continue
+					}
+				}
+				if len(failed) == len(profiles) {
+					result.err = fmt.Errorf("all profiles failed: %s", strings.Join(failed, ", "))
+				}
+				// A cancelled job is not a failure of the site
+				if errors.Is(ctx.Err(), context.Canceled) {
+					result.err = context.Canceled
+				}
+				s.partsService.FinishSiteFetch(site, result.err)
+				progress := site.Progress()
+				result.partsFetched = progress.PartsFound
+				result.partsNew = progress.PartsNew
+				result.duration = time.Since(siteStartTime)
+				results <- result
+			}(siteID)
 		}
 
-		totalParts += result.partsCount
-		totalNew += result.partsCount
-		log.Printf("[Scheduler] Site %d: Fetched %d new parts in %v", result.siteID, result.partsCount, result.duration)
-	}
+		// Collect results
+		for range siteIDs {
+			result := <-results
+			if errors.Is(result.err, context.Canceled) {
+				log.Printf("[Scheduler] Fetch from site %d was cancelled", result.siteID)
+				totalErrors++
+				continue
+			}
+			if result.err != nil {
+				log.Printf("[Scheduler] ERROR: Failed to fetch from site %d: %v", result.siteID, result.err)
+				s.partsService.NotifyFetchFailed(result.siteID, result.err)
+				totalErrors++
+				continue
+			}
+
+			totalFetched += result.partsFetched
+			totalNew += result.partsNew
+			log.Printf("[Scheduler] Site %d: Fetched %d parts, %d new, in %v", result.siteID, result.partsFetched, result.partsNew, result.duration)
+		}
+
+		// Also after cancelled or failed sites, which keep the pages they stored
+		s.partsService.clusterDuplicates()
+
+		if totalErrors == len(siteIDs) {
+			return fmt.Errorf("all %d site(s) failed", totalErrors)
+		}
+		return nil
+	})
+	log.Printf("[Scheduler] Running fetch as job %d", job.ID())
+	job.Wait()
 
 	// Log summary
 	duration := time.Since(startTime)
 	log.Println("[Scheduler] ========================================")
 	log.Printf("[Scheduler] Fetch completed in %v", duration)
+	log.Printf("[Scheduler] Total fetched parts: %d", totalFetched)
 	log.Printf("[Scheduler] Total new parts: %d", totalNew)
 	log.Printf("[Scheduler] Sites processed: %d/%d", len(siteIDs)-totalErrors, len(siteIDs))
 	if totalErrors > 0 {
 		log.Printf("[Scheduler] Errors encountered: %d", totalErrors)
 	}
 	log.Println("[Scheduler] ========================================")
-}
 
-// GetNextRuns returns the next scheduled run times
-func (s *Scheduler) GetNextRuns() []time.Time {
-	entries := s.cron.Entries()
-	nextRuns := make([]time.Time, 0, len(entries))
-	for _, entry := range entries {
-		nextRuns = append(nextRuns, entry.Next)
+	// Send one digest of the parts that matched saved searches since the last one;
+	// fetches of other sites may have run meanwhile
+	s.mu.Lock()
+	digestSince := s.digestSince
+	s.digestSince = time.Now()
+	s.mu.Unlock()
+	if err := s.partsService.SendNewPartsDigest(digestSince); err != nil {
+		log.Printf("[Scheduler] WARNING: Failed to send new parts digest: %v", err)
+	}
+
+	// Retry images that failed or did not fit in the download queue
+	if err := s.partsService.QueueMissingImages(); err != nil {
+		log.Printf("[Scheduler] WARNING: Failed to queue missing images: %v", err)
+	}
+
+	// Gone parts purged by the fetches no longer need their images
+	if err := s.partsService.PruneImages(); err != nil {
+		log.Printf("[Scheduler] WARNING: Failed to prune images: %v", err)
 	}
-	return nextRuns
 }
@@ -0,0 +1,56 @@
+package scrapers
+
+import (
+	"strings"
+	"time"
+)
+
+// RelativeDate describes a date text that is relative to today, like "Heute, 14:30"
+type RelativeDate struct {
+	Prefix     string `yaml:"prefix" json:"prefix"`
+	DaysAgo    int    `yaml:"days_ago" json:"days_ago"`
+	TimeLayout string `yaml:"time_layout" json:"time_layout"`
+}
+
+// DateFormats describes how listing date texts can be parsed
+type DateFormats struct {
+	Relative []RelativeDate `yaml:"relative" json:"relative"`
+	Layouts  []string       `yaml:"layouts" json:"layouts"`
+}
+
+// parseListingDate parses a listing date text using the given formats.
+// Relative prefixes are tried first, then the absolute layouts in order.
+func parseListingDate(dateText string, formats DateFormats, now time.Time) (time.Time, bool) {
+	dateText = strings.TrimSpace(dateText)
+	if dateText == "" {
+		return time.Time{}, false
+	}
+
+	for _, relative := range formats.Relative {
+		if !strings.HasPrefix(dateText, relative.Prefix) {
+			continue
+		}
+
+		day := now.AddDate(0, 0, -relative.DaysAgo)
+		timeStr := strings.TrimSpace(strings.TrimPrefix(dateText, relative.Prefix))
+		layout := relative.TimeLayout
+		if layout == "" {
+			layout = "15:04"
+		}
+		if t, err := time.Parse(layout, timeStr); err == nil {
+			return time.Date(
+				day.Year(), day.Month(), day.Day(),
+				t.Hour(), t.Minute(), 0, 0, now.Location(),
+			), true
+		}
+		return time.Time{}, false
+	}
+
+	for _, layout := range formats.Layouts {
+		if t, err := time.Parse(layout, dateText); err == nil {
+			return t, true
+		}
+	}
+
+	return time.Time{}, false
+}
@@ -0,0 +1,360 @@
+package scrapers
+
+import (
+	"context"
+	"dsmpartsfinder-api/siteclients"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"gopkg.in/yaml.v3"
+)
+
+// FieldSelector describes where a single field is found within a listing element.
+// An empty Selector refers to the listing element itself, an empty Attr reads the text.
+type FieldSelector struct {
+	Selector string `yaml:"selector" json:"selector"`
+	Attr     string `yaml:"attr" json:"attr"`
+}
+
+// ListingSelectors holds the selectors used to extract parts from a results page
+type ListingSelectors struct {
+	List        string        `yaml:"list" json:"list"`
+	ID          FieldSelector `yaml:"id" json:"id"`
+	URL         FieldSelector `yaml:"url" json:"url"`
+	Name        FieldSelector `yaml:"name" json:"name"`
+	Description FieldSelector `yaml:"description" json:"description"`
+	Price       FieldSelector `yaml:"price" json:"price"`
+	Date        FieldSelector `yaml:"date" json:"date"`
+	Image       FieldSelector `yaml:"image" json:"image"`
+}
+
+// Pagination describes how the scraper moves through result pages
+type Pagination struct {
+	Param     string `yaml:"param" json:"param"`
+	Start     int    `yaml:"start" json:"start"`
+	OmitFirst bool   `yaml:"omit_first" json:"omit_first"`
+	PageSize  int    `yaml:"page_size" json:"page_size"`
+	MaxPages  int    `yaml:"max_pages" json:"max_pages"`
+}
+
+// VehicleKeywords maps a vehicle to the keywords searched for it
+type VehicleKeywords struct {
+	siteclients.VehicleKey `yaml:",inline"`
+	Keywords               string `yaml:"keywords" json:"keywords"`
+}
+
+// ScraperDefinition is the per-site definition that drives an HTMLScraper.
+// It is stored as JSON or YAML in the client_config column of the site.
+// Keywords is searched when no entry in Vehicles matches the search parameters.
+type ScraperDefinition struct {
+	BaseURL       string            `yaml:"base_url" json:"base_url"`
+	SearchPath    string            `yaml:"search_path" json:"search_path"`
+	QueryParams   map[string]string `yaml:"query_params" json:"query_params"`
+	KeywordsParam string            `yaml:"keywords_param" json:"keywords_param"`
+	Keywords      string            `yaml:"keywords" json:"keywords"`
+	Vehicles      []VehicleKeywords `yaml:"vehicles" json:"vehicles"`
+	Headers       map[string]string `yaml:"headers" json:"headers"`
+	Selectors     ListingSelectors  `yaml:"selectors" json:"selectors"`
+	DateFormats   DateFormats       `yaml:"date_formats" json:"date_formats"`
+	Pagination    Pagination        `yaml:"pagination" json:"pagination"`
+	Currency      string            `yaml:"currency" json:"currency"`
+}
+
+// ParseScraperDefinition parses a JSON or YAML scraper definition and validates it
+func ParseScraperDefinition(data string) (*ScraperDefinition, error) {
+	var def ScraperDefinition
+	// YAML is a superset of JSON, so both formats go through the YAML decoder
+	if err := yaml.Unmarshal([]byte(data), &def); err != nil {
+		return nil, fmt.Errorf("failed to parse scraper definition: %w", err)
+	}
+
+	if def.BaseURL == "" {
+		return nil, fmt.Errorf("scraper definition is missing base_url")
+	}
+	if def.Selectors.List == "" {
+		return nil, fmt.Errorf("scraper definition is missing selectors.list")
+	}
+	if def.Selectors.ID.Selector == "" && def.Selectors.ID.Attr == "" {
+		return nil, fmt.Errorf("scraper definition is missing selectors.id")
+	}
+	if def.Selectors.Name.Selector == "" && def.Selectors.Name.Attr == "" {
+		return nil, fmt.Errorf("scraper definition is missing selectors.name")
+	}
+
+	def.BaseURL = strings.TrimSuffix(def.BaseURL, "/")
+	if def.Pagination.Start == 0 {
+		def.Pagination.Start = 1
+	}
+	if def.Pagination.MaxPages == 0 {
+		def.Pagination.MaxPages = 100
+	}
+	if def.Currency == "" {
+		def.Currency = "EUR"
+	}
+
+	return &def, nil
+}
+
+func init() {
+	siteclients.Register("html", func(config siteclients.ClientConfig) (siteclients.SiteClient, error) {
+		def, err := ParseScraperDefinition(config.Config)
+		if err != nil {
+			return nil, err
+		}
+		return NewHTMLScraper(config.SiteID, config.Name, def), nil
+	})
+}
+
+// HTMLScraper implements scraping for any classifieds site described by a ScraperDefinition
+type HTMLScraper struct {
+	name       string
+	definition *ScraperDefinition
+	httpClient *http.Client
+	siteID     int
+}
+
+// NewHTMLScraper creates a new scraper for the given definition
+func NewHTMLScraper(siteID int, name string, definition *ScraperDefinition) *HTMLScraper {
+	return &HTMLScraper{
+		name:       name,
+		definition: definition,
+		httpClient: siteclients.CreateHTTPClient(),
+		siteID:     siteID,
+	}
+}
+
+// GetName returns the name of the site client
+func (c *HTMLScraper) GetName() string {
+	return c.name
+}
+
+// GetSiteID returns the database ID of the site
+func (c *HTMLScraper) GetSiteID() int {
+	return c.siteID
+}
+
+// keywordSearches returns the keywords to search for the given parameters
+func (c *HTMLScraper) keywordSearches(params siteclients.SearchParams) []string {
+	table := make(siteclients.VehicleTable[string], 0, len(c.definition.Vehicles))
+	for _, vehicle := range c.definition.Vehicles {
+		table = append(table, siteclients.VehicleMapping[string]{VehicleKey: vehicle.VehicleKey, Value: vehicle.Keywords})
+	}
+	if keywords := table.Match(params); len(keywords) > 0 {
+		return keywords
+	}
+	if c.definition.Keywords != "" {
+		return []string{c.definition.Keywords}
+	}
+	return siteclients.MatchKeywords(nil, params)
+}
+
+// FetchParts fetches parts based on search parameters
+func (c *HTMLScraper) FetchParts(ctx context.Context, params siteclients.SearchParams) ([]siteclients.Part, error) {
+	return siteclients.CollectParts(ctx, c, params)
+}
+
+// StreamParts runs one search per matching keyword, handing over every page as soon as it is parsed.
+// Sites without a keywords parameter are fetched once.
+func (c *HTMLScraper) StreamParts(ctx context.Context, params siteclients.SearchParams, handle siteclients.PageHandler) error {
+	log.Printf("[HTMLScraper:%s] Starting fetch with params: %+v", c.name, params)
+
+	keywordSearches := []string{""}
+	if c.definition.KeywordsParam != "" {
+		keywordSearches = c.keywordSearches(params)
+		if len(keywordSearches) == 0 {
+			return fmt.Errorf("search params do not describe a vehicle")
+		}
+	}
+
+	stream := siteclients.NewPartStream(params.Limit, handle)
+	for _, keywords := range keywordSearches {
+		if err := c.searchParts(ctx, keywords, stream); err != nil {
+			return err
+		}
+		if stream.Done() {
+			log.Printf("[HTMLScraper:%s] Reached limit of %d parts, stopping", c.name, params.Limit)
+			break
+		}
+	}
+
+	log.Printf("[HTMLScraper:%s] Finished fetching. Total parts: %d", c.name, stream.Count())
+	return nil
+}
+
+// searchParts fetches parts page by page until a page comes back empty or short
+func (c *HTMLScraper) searchParts(ctx context.Context, keywords string, stream *siteclients.PartStream) error {
+	pagination := c.definition.Pagination
+	pagesFetched := 0
+
+	for page := pagination.Start; pagesFetched < pagination.MaxPages; page++ {
+		searchURL := c.buildSearchURL(keywords, page)
+		log.Printf("[HTMLScraper:%s] Page %d URL: %s", c.name, page, searchURL)
+
+		pageParts, err := c.fetchSinglePage(ctx, searchURL)
+		if err != nil {
+			return fmt.Errorf("failed to fetch page %d: %w", page, err)
+		}
+		pagesFetched++
+
+		if len(pageParts) == 0 {
+			log.Printf("[HTMLScraper:%s] No more parts found on page %d, stopping", c.name, page)
+			break
+		}
+
+		done, err := stream.Emit(pageParts)
+		if err != nil {
+			return err
+		}
+		if done {
+			break
+		}
+
+		// Without a pagination parameter there is only one page
+		if pagination.Param == "" {
+			break
+		}
+
+		// If we got fewer parts than a full page, this is the last page
+		if pagination.PageSize > 0 && len(pageParts) < pagination.PageSize {
+			break
+		}
+	}
+
+	return nil
+}
+
+// buildSearchURL constructs the search URL for the given keywords and page number
+func (c *HTMLScraper) buildSearchURL(keywords string, page int) string {
+	def := c.definition
+
+	queryParams := url.Values{}
+	for key, value := range def.QueryParams {
+		queryParams.Set(key, value)
+	}
+	if def.KeywordsParam != "" {
+		queryParams.Set(def.KeywordsParam, keywords)
+	}
+	if def.Pagination.Param != "" && !(def.Pagination.OmitFirst && page == def.Pagination.Start) {
+		queryParams.Set(def.Pagination.Param, fmt.Sprintf("%d", page))
+	}
+
+	searchURL := def.BaseURL + def.SearchPath
+	if encoded := queryParams.Encode(); encoded != "" {
+		searchURL += "?" + encoded
+	}
+	return searchURL
+}
+
+// fetchSinglePage fetches and parses a single results page
+func (c *HTMLScraper) fetchSinglePage(ctx context.Context, searchURL string) ([]siteclients.Part, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", searchURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/118.0.0.0 Safari/537.36")
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,image/webp,*/*;q=0.8")
+	for key, value := range c.definition.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+	}
+
+	parts := make([]siteclients.Part, 0)
+	doc.Find(c.definition.Selectors.List).Each(func(i int, s *goquery.Selection) {
+		part, err := c.extractPart(s)
+		if err != nil {
+			log.Printf("[HTMLScraper:%s] Warning: failed to extract part %d: %v", c.name, i, err)
+			return
+		}
+		parts = append(parts, part)
+	})
+
+	log.Printf("[HTMLScraper:%s] Extracted %d parts from page", c.name, len(parts))
+	return parts, nil
+}
+
+// extractPart extracts part information from a listing element
+func (c *HTMLScraper) extractPart(s *goquery.Selection) (siteclients.Part, error) {
+	selectors := c.definition.Selectors
+	part := siteclients.Part{
+		SiteID: c.siteID,
+	}
+
+	part.ID = extractField(s, selectors.ID)
+	if part.ID == "" {
+		return part, fmt.Errorf("missing id")
+	}
+
+	part.Name = extractField(s, selectors.Name)
+	if part.Name == "" {
+		return part, fmt.Errorf("missing name")
+	}
+
+	if link := extractField(s, selectors.URL); link != "" {
+		part.URL = c.resolveURL(link)
+	}
+	part.Description = extractField(s, selectors.Description)
+	part.Price = extractField(s, selectors.Price)
+	if price, ok := siteclients.ParsePrice(part.Price, c.definition.Currency); ok {
+		part.SetPrice(price)
+	}
+
+	if dateText := extractField(s, selectors.Date); dateText != "" {
+		if creationDate, ok := parseListingDate(dateText, c.definition.DateFormats, time.Now()); ok {
+			part.CreationDate = creationDate
+		} else {
+			log.Printf("[HTMLScraper:%s] WARNING: Could not parse date text '%s'", c.name, dateText)
+		}
+	}
+
+	if imgSrc := extractField(s, selectors.Image); imgSrc != "" {
+		part.ImageURL = c.resolveURL(imgSrc)
+	}
+
+	return part, nil
+}
+
+// resolveURL turns a site-relative link into an absolute URL
+func (c *HTMLScraper) resolveURL(link string) string {
+	if strings.HasPrefix(link, "/") && !strings.HasPrefix(link, "//") {
+		return c.definition.BaseURL + link
+	}
+	return link
+}
+
+// extractField reads a single field from a listing element
+func extractField(s *goquery.Selection, field FieldSelector) string {
+	if field.Selector == "" && field.Attr == "" {
+		return ""
+	}
+
+	target := s
+	if field.Selector != "" {
+		target = s.Find(field.Selector).First()
+	}
+
+	if field.Attr != "" {
+		value, _ := target.Attr(field.Attr)
+		return strings.TrimSpace(value)
+	}
+	return strings.TrimSpace(target.Text())
+}
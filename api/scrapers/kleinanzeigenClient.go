@@ -3,7 +3,6 @@ package scrapers
 import (
 	"context"
 	"dsmpartsfinder-api/siteclients"
-	"encoding/base64"
 	"fmt"
 	"io"
 	"log"
@@ -15,6 +14,20 @@ import (
 	"github.com/PuerkitoBio/goquery"
 )
 
+// kleinanzeigenDateFormats are the date texts Kleinanzeigen shows on listings
+var kleinanzeigenDateFormats = DateFormats{
+	Relative: []RelativeDate{
+		{Prefix: "Heute, ", DaysAgo: 0, TimeLayout: "15:04"},
+		{Prefix: "Gestern, ", DaysAgo: 1, TimeLayout: "15:04"},
+	},
+	Layouts: []string{
+		"02.01.2006",        // DD.MM.YYYY
+		"2.1.2006",          // D.M.YYYY
+		"02.01.2006, 15:04", // DD.MM.YYYY, HH:mm (fallback)
+		"2.1.2006, 15:04",   // D.M.YYYY, HH:mm (fallback)
+	},
+}
+
 // KleinanzeigenClient implements scraping for kleinanzeigen.de
 type KleinanzeigenClient struct {
 	baseURL    string
@@ -22,6 +35,12 @@ type KleinanzeigenClient struct {
 	siteID     int
 }
 
+func init() {
+	siteclients.Register("kleinanzeigen", func(config siteclients.ClientConfig) (siteclients.SiteClient, error) {
+		return NewKleinanzeigenClient(config.SiteID), nil
+	})
+}
+
 // NewKleinanzeigenClient creates a new Kleinanzeigen scraper client
 func NewKleinanzeigenClient(siteID int) *KleinanzeigenClient {
 	return &KleinanzeigenClient{
@@ -41,12 +60,50 @@ func (c *KleinanzeigenClient) GetSiteID() int {
 	return c.siteID
 }
 
+// kleinanzeigenVehicleKeywords translates vehicles into Kleinanzeigen search keywords
+var kleinanzeigenVehicleKeywords = siteclients.VehicleTable[string]{
+	{VehicleKey: siteclients.VehicleKey{Make: "Mitsubishi", BaseModel: "Eclipse", YearFrom: 1989, YearTo: 1994}, Value: "Mitsubishi Eclipse D20"},
+	{VehicleKey: siteclients.VehicleKey{Make: "Mitsubishi", BaseModel: "Eclipse", YearFrom: 1995, YearTo: 1999}, Value: "Mitsubishi Eclipse D30"},
+	{VehicleKey: siteclients.VehicleKey{Make: "Eagle", BaseModel: "Talon", YearFrom: 1989, YearTo: 1998}, Value: "Eagle Talon"},
+	{VehicleKey: siteclients.VehicleKey{Make: "Plymouth", BaseModel: "Laser", YearFrom: 1989, YearTo: 1994}, Value: "Plymouth Laser"},
+	{VehicleKey: siteclients.VehicleKey{Make: "Mitsubishi", BaseModel: "Galant", Model: "VR-4", YearFrom: 1988, YearTo: 1992}, Value: "Galant VR4"},
+}
+
 // FetchParts fetches parts from Kleinanzeigen based on search parameters
-// Automatically fetches all pages until no more results are found
 func (c *KleinanzeigenClient) FetchParts(ctx context.Context, params siteclients.SearchParams) ([]siteclients.Part, error) {
+	return siteclients.CollectParts(ctx, c, params)
+}
+
+// StreamParts fetches parts from Kleinanzeigen, handing over every page as soon as it is parsed.
+// Runs one search per matching keyword and merges the results
+func (c *KleinanzeigenClient) StreamParts(ctx context.Context, params siteclients.SearchParams, handle siteclients.PageHandler) error {
 	log.Printf("[KleinanzeigenClient] Starting fetch with params: %+v", params)
 
-	allParts := make([]siteclients.Part, 0)
+	keywordSearches := siteclients.MatchKeywords(kleinanzeigenVehicleKeywords, params)
+	if len(keywordSearches) == 0 {
+		return fmt.Errorf("search params do not describe a vehicle")
+	}
+
+	stream := siteclients.NewPartStream(params.Limit, handle)
+	for _, keywords := range keywordSearches {
+		if err := c.searchParts(ctx, keywords, stream); err != nil {
+			return err
+		}
+		if stream.Done() {
+			log.Printf("[KleinanzeigenClient] Reached limit of %d parts, stopping", params.Limit)
+			break
+		}
+	}
+
+	log.Printf("[KleinanzeigenClient] Finished fetching. Total parts: %d", stream.Count())
+	return nil
+}
+
+// searchParts searches for the given keywords
+// Automatically fetches all pages until no more results are found
+func (c *KleinanzeigenClient) searchParts(ctx context.Context, keywords string, stream *siteclients.PartStream) error {
+	log.Printf("[KleinanzeigenClient] Searching for '%s'", keywords)
+
 	page := 1
 	maxPages := 100    // Safety limit to prevent infinite loops
 	itemsPerPage := 25 // Kleinanzeigen shows 25 items per page
@@ -55,9 +112,9 @@ func (c *KleinanzeigenClient) FetchParts(ctx context.Context, params siteclients
 		log.Printf("[KleinanzeigenClient] Fetching page %d...", page)
 
 		// Build search URL with page number
-		searchURL, err := c.buildSearchURLWithPage(params, page)
+		searchURL, err := c.buildSearchURLWithPage(keywords, page)
 		if err != nil {
-			return nil, fmt.Errorf("failed to build search URL: %w", err)
+			return fmt.Errorf("failed to build search URL: %w", err)
 		}
 
 		log.Printf("[KleinanzeigenClient] Page %d URL: %s", page, searchURL)
@@ -65,7 +122,7 @@ func (c *KleinanzeigenClient) FetchParts(ctx context.Context, params siteclients
 		// Fetch the page
 		pageParts, err := c.fetchSinglePage(ctx, searchURL)
 		if err != nil {
-			return nil, fmt.Errorf("failed to fetch page %d: %w", page, err)
+			return fmt.Errorf("failed to fetch page %d: %w", page, err)
 		}
 
 		log.Printf("[KleinanzeigenClient] Page %d: got %d parts", page, len(pageParts))
@@ -76,7 +133,14 @@ func (c *KleinanzeigenClient) FetchParts(ctx context.Context, params siteclients
 			break
 		}
 
-		allParts = append(allParts, pageParts...)
+		// Hand the page over before fetching the next one
+		done, err := stream.Emit(pageParts)
+		if err != nil {
+			return err
+		}
+		if done {
+			break
+		}
 
 		// If we got fewer parts than a full page, this is the last page
 		if len(pageParts) < itemsPerPage {
@@ -84,18 +148,10 @@ func (c *KleinanzeigenClient) FetchParts(ctx context.Context, params siteclients
 			break
 		}
 
-		// Check if limit is set and we've reached it
-		if params.Limit > 0 && len(allParts) >= params.Limit {
-			log.Printf("[KleinanzeigenClient] Reached limit of %d parts, stopping", params.Limit)
-			allParts = allParts[:params.Limit]
-			break
-		}
-
 		page++
 	}
 
-	log.Printf("[KleinanzeigenClient] Finished fetching. Total parts: %d from %d page(s)", len(allParts), page)
-	return allParts, nil
+	return nil
 }
 
 // fetchSinglePage fetches and parses a single page
@@ -145,7 +201,7 @@ func (c *KleinanzeigenClient) fetchSinglePage(ctx context.Context, searchURL str
 	}
 
 	doc.Find(selector).Each(func(i int, s *goquery.Selection) {
-		part, err := c.extractPart(ctx, s)
+		part, err := c.extractPart(s)
 		if err != nil {
 			log.Printf("[KleinanzeigenClient] Warning: failed to extract part %d: %v", i, err)
 			return
@@ -158,10 +214,7 @@ func (c *KleinanzeigenClient) fetchSinglePage(ctx context.Context, searchURL str
 }
 
 // buildSearchURLWithPage constructs the search URL with parameters and page number
-func (c *KleinanzeigenClient) buildSearchURLWithPage(params siteclients.SearchParams, page int) (string, error) {
-	// Build the search keywords
-	keywords := "Mitsubishi Eclipse D30"
-
+func (c *KleinanzeigenClient) buildSearchURLWithPage(keywords string, page int) (string, error) {
 	// Build query parameters
 	queryParams := url.Values{}
 	queryParams.Set("categoryId", "223") // Auto parts category
@@ -187,55 +240,15 @@ func (c *KleinanzeigenClient) buildSearchURLWithPage(params siteclients.SearchPa
 }
 
 // extractPart extracts part information from an article element
-func (c *KleinanzeigenClient) extractPart(ctx context.Context, s *goquery.Selection) (siteclients.Part, error) {
+func (c *KleinanzeigenClient) extractPart(s *goquery.Selection) (siteclients.Part, error) {
 	part := siteclients.Part{
 		SiteID: c.siteID,
 	}
 
 	// Extract creation date from the date container
-	dateContainer := s.Find(".aditem-main--top--right")
-	dateText := strings.TrimSpace(dateContainer.Text())
-
+	dateText := strings.TrimSpace(s.Find(".aditem-main--top--right").Text())
 	if dateText != "" {
-		var creationDate time.Time
-		now := time.Now()
-
-		// Handle "Heute, HH:mm" format
-		if strings.HasPrefix(dateText, "Heute, ") {
-			timeStr := strings.TrimPrefix(dateText, "Heute, ")
-			if t, err := time.Parse("15:04", timeStr); err == nil {
-				creationDate = time.Date(
-					now.Year(), now.Month(), now.Day(),
-					t.Hour(), t.Minute(), 0, 0, now.Location(),
-				)
-			}
-		} else if strings.HasPrefix(dateText, "Gestern, ") {
-			// Handle "Gestern, HH:mm" format
-			timeStr := strings.TrimPrefix(dateText, "Gestern, ")
-			if t, err := time.Parse("15:04", timeStr); err == nil {
-				yesterday := now.AddDate(0, 0, -1)
-				creationDate = time.Date(
-					yesterday.Year(), yesterday.Month(), yesterday.Day(),
-					t.Hour(), t.Minute(), 0, 0, now.Location(),
-				)
-			}
-		} else if strings.Contains(dateText, ".") {
-			// Handle "DD.MM.YYYY" format
-			layouts := []string{
-				"02.01.2006",        // DD.MM.YYYY
-				"2.1.2006",          // D.M.YYYY
-				"02.01.2006, 15:04", // DD.MM.YYYY, HH:mm (fallback)
-				"2.1.2006, 15:04",   // D.M.YYYY, HH:mm (fallback)
-			}
-			for _, layout := range layouts {
-				if t, err := time.Parse(layout, dateText); err == nil {
-					creationDate = t
-					break
-				}
-			}
-		}
-
-		if !creationDate.IsZero() {
+		if creationDate, ok := parseListingDate(dateText, kleinanzeigenDateFormats, time.Now()); ok {
 			part.CreationDate = creationDate
 		} else {
 			log.Printf("[KleinanzeigenClient] WARNING: Could not parse date text '%s'", dateText)
@@ -274,56 +287,21 @@ func (c *KleinanzeigenClient) extractPart(ctx context.Context, s *goquery.Select
 	price := s.Find("p.aditem-main--middle--price-shipping--price").Text()
 	price = strings.TrimSpace(price)
 	part.Price = price
+	if parsedPrice, ok := siteclients.ParsePrice(price, "EUR"); ok {
+		part.SetPrice(parsedPrice)
+	}
 
 	// part.TypeName = "Eclipse (D30)"
 
 	// Extract image URL
 	imgSrc, exists := s.Find(".imagebox img").Attr("src")
 	if exists && imgSrc != "" {
-		// Fetch and convert image to base64
-		imageBase64, err := c.fetchImageAsBase64(ctx, imgSrc)
-		if err != nil {
-			log.Printf("[KleinanzeigenClient] Warning: failed to fetch image for part %s: %v", adID, err)
-		} else {
-			part.ImageBase64 = imageBase64
+		// Images are downloaded by the parts service after the part is stored
+		if strings.HasPrefix(imgSrc, "//") {
+			imgSrc = "https:" + imgSrc
 		}
+		part.ImageURL = imgSrc
 	}
 
 	return part, nil
 }
-
-// fetchImageAsBase64 fetches an image and returns it as base64
-func (c *KleinanzeigenClient) fetchImageAsBase64(ctx context.Context, imageURL string) (string, error) {
-	// Handle protocol-relative URLs
-	if strings.HasPrefix(imageURL, "//") {
-		imageURL = "https:" + imageURL
-	}
-
-	req, err := http.NewRequestWithContext(ctx, "GET", imageURL, nil)
-	if err != nil {
-		return "", fmt.Errorf("failed to create image request: %w", err)
-	}
-
-	req.Header.Set("User-Agent", "Mozilla/5.0 (X11; Linux x86_64; rv:143.0) Gecko/20100101 Firefox/143.0")
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("failed to fetch image: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("unexpected status code for image: %d", resp.StatusCode)
-	}
-
-	// Read image data
-	imageData, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to read image data: %w", err)
-	}
-
-	// Convert to base64
-	base64String := base64.StdEncoding.EncodeToString(imageData)
-
-	return base64String, nil
-}
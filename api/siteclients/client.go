@@ -2,22 +2,31 @@ package siteclients
 
 import (
 	"context"
-	"crypto/tls"
 	"net/http"
 	"time"
 )
 
 // Part represents a car part from any site client
 type Part struct {
-	ID           string    `json:"id"`
-	Description  string    `json:"description"`
-	TypeName     string    `json:"type_name"`
-	Name         string    `json:"name"`
-	ImageBase64  string    `json:"image_base64"`
-	URL          string    `json:"url"`
-	SiteID       int       `json:"site_id"`
-	Price        string    `json:"price"`
-	CreationDate time.Time `json:"creation_date"`
+	ID            string    `json:"id"`
+	Description   string    `json:"description"`
+	TypeName      string    `json:"type_name"`
+	Name          string    `json:"name"`
+	ImageURL      string    `json:"image_url"`
+	URL           string    `json:"url"`
+	SiteID        int       `json:"site_id"`
+	Price         string    `json:"price"`
+	PriceAmount   int64     `json:"price_amount"`
+	PriceCurrency string    `json:"price_currency"`
+	PriceType     PriceType `json:"price_type"`
+	CreationDate  time.Time `json:"creation_date"`
+}
+
+// SetPrice sets the structured price fields of the part; an empty PriceType means the price is unknown
+func (p *Part) SetPrice(price Price) {
+	p.PriceAmount = price.Amount
+	p.PriceCurrency = price.Currency
+	p.PriceType = price.Type
 }
 
 // SearchParams represents the search parameters for finding parts
@@ -44,11 +53,11 @@ type SiteClient interface {
 	GetSiteID() int
 }
 
+// CreateHTTPClient returns an HTTP client backed by the shared resilient transport.
+// Timeouts apply per attempt, so retries and rate limit waits are bounded by the
+// request context instead of a single client timeout.
 func CreateHTTPClient() *http.Client {
 	return &http.Client{
-		Timeout: 30 * time.Second,
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-		},
+		Transport: sharedTransport,
 	}
 }
@@ -4,11 +4,13 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"net/url"
+	"os"
 	"strings"
 	"time"
 )
@@ -94,6 +96,14 @@ type ebayAPIError struct {
 	} `json:"errors"`
 }
 
+func init() {
+	Register("ebay", func(config ClientConfig) (SiteClient, error) {
+		clientID := os.Getenv("EBAY_CLIENT_ID")
+		clientSecret := os.Getenv("EBAY_CLIENT_SECRET")
+		return NewEbayClient(config.SiteID, clientID, clientSecret, false), nil
+	})
+}
+
 // NewEbayClient creates a new EbayClient
 func NewEbayClient(siteID int, appID string, clientSecret string, isSandbox bool) *EbayClient {
 	return &EbayClient{
@@ -134,8 +144,7 @@ func (c *EbayClient) GetAccessToken() error {
 	req.Header.Set("Authorization", "Basic "+auth)
 
 	// Send request
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return fmt.Errorf("failed to get token: %w", err)
 	}
@@ -169,59 +178,104 @@ func (c *EbayClient) GetSiteID() int {
 	return c.siteID
 }
 
+// ebayVehicleKeywords translates vehicles into eBay search queries.
+// Parenthesised, comma separated terms are OR-ed by the Browse API.
+var ebayVehicleKeywords = VehicleTable[string]{
+	{VehicleKey: VehicleKey{Make: "Mitsubishi", BaseModel: "Eclipse", YearFrom: 1989, YearTo: 1994}, Value: "(Mitsubishi Eclipse 1g, D27A)"},
+	{VehicleKey: VehicleKey{Make: "Mitsubishi", BaseModel: "Eclipse", YearFrom: 1995, YearTo: 1999}, Value: "(Mitsubishi Eclipse 2g, D32A)"},
+	{VehicleKey: VehicleKey{Make: "Eagle", BaseModel: "Talon", YearFrom: 1989, YearTo: 1998}, Value: "Eagle Talon"},
+	{VehicleKey: VehicleKey{Make: "Plymouth", BaseModel: "Laser", YearFrom: 1989, YearTo: 1994}, Value: "Plymouth Laser"},
+	{VehicleKey: VehicleKey{Make: "Mitsubishi", BaseModel: "Galant", Model: "VR-4", YearFrom: 1988, YearTo: 1992}, Value: "(Galant VR-4, Galant VR4)"},
+}
+
 // FetchParts fetches parts from eBay based on search parameters
 func (c *EbayClient) FetchParts(ctx context.Context, params SearchParams) ([]Part, error) {
+	return CollectParts(ctx, c, params)
+}
+
+// StreamParts fetches parts from eBay, handing over every page of results as it arrives
+func (c *EbayClient) StreamParts(ctx context.Context, params SearchParams, handle PageHandler) error {
+	queries := MatchKeywords(ebayVehicleKeywords, params)
+	if len(queries) == 0 {
+		return fmt.Errorf("search params do not describe a vehicle")
+	}
+
 	log.Println("Fetching parts from eBay")
 	c.GetAccessToken()
 	log.Println("Access token retrieved")
 
-	allParts := []Part{}
-	offset := 0
-	for {
-		// Build query parameters
-		query := url.Values{}
-		query.Set("sort", "newlyListed")
-		query.Set("limit", "200")
-		query.Set("offset", fmt.Sprintf("%d", offset))
-		query.Set("q", "(Mitsubishi Eclipse 2g, D32A)")
-		query.Set("category_ids", "6030")
-
-		apiURL := fmt.Sprintf("https://api.ebay.com/buy/browse/v1/item_summary/search?%s", query.Encode())
-		req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create request: %w", err)
+	stream := NewPartStream(params.Limit, handle)
+	for _, q := range queries {
+		log.Printf("Searching eBay for %s", q)
+		if err := c.searchParts(ctx, q, params, stream); err != nil {
+			return err
+		}
+		if stream.Done() {
+			break
 		}
+	}
+	return nil
+}
 
-		// Add the access token to the request header
-		req.Header.Set("Authorization", "Bearer "+c.accessToken)
+// searchPage fetches one page of results of an eBay search query. The response body
+// is closed before returning, so the host slot of the transport is freed for the
+// next page.
+func (c *EbayClient) searchPage(ctx context.Context, q string, offset int) (*EbayBrowseResponse, error) {
+	// Build query parameters
+	query := url.Values{}
+	query.Set("sort", "newlyListed")
+	query.Set("limit", "200")
+	query.Set("offset", fmt.Sprintf("%d", offset))
+	query.Set("q", q)
+	query.Set("category_ids", "6030")
+
+	apiURL := fmt.Sprintf("https://api.ebay.com/buy/browse/v1/item_summary/search?%s", query.Encode())
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
 
-		resp, err := c.httpClient.Do(req)
-		if err != nil {
-			return nil, fmt.Errorf("failed to execute request: %w", err)
-		}
-		defer resp.Body.Close()
-
-		if resp.StatusCode != http.StatusOK {
-			// Try to extract error message from body
-			body, _ := io.ReadAll(resp.Body)
-			var apiErr ebayAPIError
-			msg := fmt.Sprintf("unexpected status code: %d", resp.StatusCode)
-			if len(body) > 0 {
-				if err := json.Unmarshal(body, &apiErr); err == nil && len(apiErr.Errors) > 0 {
-					msg += ": " + apiErr.Errors[0].Message
-					if apiErr.Errors[0].LongMessage != "" {
-						msg += " (" + apiErr.Errors[0].LongMessage + ")"
-					}
-				} else {
-					msg += ": " + string(body)
+	// Add the access token to the request header
+	req.Header.Set("Authorization", "Bearer "+c.accessToken)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		// Try to extract error message from body
+		body, _ := io.ReadAll(resp.Body)
+		var apiErr ebayAPIError
+		msg := fmt.Sprintf("unexpected status code: %d", resp.StatusCode)
+		if len(body) > 0 {
+			if err := json.Unmarshal(body, &apiErr); err == nil && len(apiErr.Errors) > 0 {
+				msg += ": " + apiErr.Errors[0].Message
+				if apiErr.Errors[0].LongMessage != "" {
+					msg += " (" + apiErr.Errors[0].LongMessage + ")"
 				}
+			} else {
+				msg += ": " + string(body)
 			}
-			return nil, fmt.Errorf(msg)
 		}
+		return nil, errors.New(msg)
+	}
 
-		var apiResponse EbayBrowseResponse
-		if err := json.NewDecoder(resp.Body).Decode(&apiResponse); err != nil {
-			return nil, fmt.Errorf("failed to decode response: %w", err)
+	var apiResponse EbayBrowseResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResponse); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &apiResponse, nil
+}
+
+// searchParts runs a single eBay search query and pages through all results
+func (c *EbayClient) searchParts(ctx context.Context, q string, params SearchParams, stream *PartStream) error {
+	offset := params.Offset
+	for {
+		apiResponse, err := c.searchPage(ctx, q, offset)
+		if err != nil {
+			return err
 		}
 
 		// Convert eBay items to Part structs
@@ -234,46 +288,30 @@ func (c *EbayClient) FetchParts(ctx context.Context, params SearchParams) ([]Par
 				Name:         item.Title,
 				URL:          item.ItemWebURL,
 				SiteID:       c.siteID,
-				Price:        "€ " + item.Price.Value,
+				Price:        item.Price.Currency + " " + item.Price.Value,
 				CreationDate: item.ItemOriginDate,
 			}
-			// Fetch and convert image to base64
-			if len(item.ThumbnailImages) > 0 && item.ThumbnailImages[0].ImageURL != "" {
-				imageBase64, fetchErr := c.fetchImageAsBase64(ctx, item.ThumbnailImages[0].ImageURL)
-				if fetchErr == nil {
-					part.ImageBase64 = imageBase64
-				}
+			// eBay returns the amount as a decimal string with a separate ISO currency
+			if amount, err := ParseAmount(item.Price.Value); err == nil {
+				part.SetPrice(Price{Amount: amount, Currency: item.Price.Currency, Type: PriceFixed})
+				part.Price = FormatPrice(amount, item.Price.Currency)
+			}
+			// Images are downloaded by the parts service after the part is stored
+			if len(item.ThumbnailImages) > 0 {
+				part.ImageURL = item.ThumbnailImages[0].ImageURL
 			}
 			parts = append(parts, part)
 		}
-		allParts = append(allParts, parts...)
+		done, err := stream.Emit(parts)
+		if err != nil {
+			return err
+		}
 
 		// If less than 200 results returned, we're done
-		if len(parts) < 200 {
+		if done || len(parts) < 200 {
 			break
 		}
 		offset += 200
 	}
-	return allParts, nil
-}
-
-// fetchImageAsBase64 fetches an image from a URL and returns it as a base64 string
-func (c *EbayClient) fetchImageAsBase64(ctx context.Context, imageURL string) (string, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", imageURL, nil)
-	if err != nil {
-		return "", fmt.Errorf("failed to create image request: %w", err)
-	}
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("failed to fetch image: %w", err)
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("unexpected status code for image: %d", resp.StatusCode)
-	}
-	imageData, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to read image data: %w", err)
-	}
-	return base64.StdEncoding.EncodeToString(imageData), nil
+	return nil
 }
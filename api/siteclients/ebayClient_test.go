@@ -0,0 +1,61 @@
+package siteclients
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// redirectTransport sends every request to a test server, keeping the original
+// host for the host limits of the transport wrapping it
+type redirectTransport struct {
+	target *url.URL
+}
+
+func (t redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = t.target.Scheme
+	req.URL.Host = t.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestEbaySearchPartsPagesBeyondMaxConcurrent(t *testing.T) {
+	const total = 1010 // six pages of at most 200
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		var response EbayBrowseResponse
+		for i := offset; i < total && i < offset+200; i++ {
+			item := EbayItem{ItemID: fmt.Sprintf("item-%d", i), Title: "TD05 turbo"}
+			item.Price.Value = "100.00"
+			item.Price.Currency = "EUR"
+			response.ItemSummaries = append(response.ItemSummaries, item)
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	target, _ := url.Parse(server.URL)
+	transport := NewResilientTransport(redirectTransport{target: target}, testTransportConfig(4))
+	client := &EbayClient{httpClient: &http.Client{Transport: transport}, siteID: 3}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	fetched := 0
+	stream := NewPartStream(0, func(parts []Part) error {
+		fetched += len(parts)
+		return nil
+	})
+	if err := client.searchParts(ctx, "Eclipse", SearchParams{}, stream); err != nil {
+		t.Fatal(err)
+	}
+	if fetched != total {
+		t.Errorf("fetched %d parts, want %d", fetched, total)
+	}
+}
@@ -0,0 +1,163 @@
+package siteclients
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// PriceType describes how a listing is priced
+type PriceType string
+
+const (
+	PriceFixed      PriceType = "fixed"
+	PriceNegotiable PriceType = "negotiable"
+	PriceFree       PriceType = "free"
+	PriceOnRequest  PriceType = "on_request"
+)
+
+// Price is a parsed listing price. Amount is in cents and only meaningful for
+// fixed, negotiable and free prices.
+type Price struct {
+	Amount   int64
+	Currency string
+	Type     PriceType
+}
+
+// Marker words in German, Dutch and English, matched against the lower-cased price text
+var (
+	freePriceMarkers       = []string{"zu verschenken", "verschenke", "kostenlos", "gratis", "free"}
+	onRequestPriceMarkers  = []string{"auf anfrage", "op aanvraag", "on request", "call for price"}
+	negotiablePriceMarkers = []string{"vb", "verhandlungsbasis", "verhandelbar", "n.o.t.k", "notk", "bieden", "ono", "obo", "or best offer"}
+)
+
+// currencySymbols maps the currency notations found in price texts to ISO codes
+var currencySymbols = []struct {
+	notation string
+	code     string
+}{
+	{"€", "EUR"}, {"eur", "EUR"},
+	{"£", "GBP"}, {"gbp", "GBP"},
+	{"us $", "USD"}, {"$", "USD"}, {"usd", "USD"},
+	{"chf", "CHF"},
+	{"zł", "PLN"}, {"pln", "PLN"},
+}
+
+var amountPattern = regexp.MustCompile(`\d[\d.,' ]*`)
+
+// ParsePrice parses a price text like "150 € VB", "1.250,00 €" or "Zu verschenken".
+// Texts without a currency are assumed to be in defaultCurrency. It reports false
+// when the text holds no recognisable price.
+func ParsePrice(text, defaultCurrency string) (Price, bool) {
+	lower := strings.ToLower(strings.TrimSpace(text))
+	if lower == "" {
+		return Price{}, false
+	}
+
+	price := Price{Currency: defaultCurrency, Type: PriceFixed}
+	for _, symbol := range currencySymbols {
+		if strings.Contains(lower, symbol.notation) {
+			price.Currency = symbol.code
+			break
+		}
+	}
+
+	var amount int64
+	var hasAmount bool
+	if match := amountPattern.FindString(lower); match != "" {
+		parsed, err := ParseAmount(match)
+		if err != nil {
+			return Price{}, false
+		}
+		amount, hasAmount = parsed, true
+	}
+
+	// An amount wins over "free" elsewhere in the text, as in "50 € free shipping"
+	if (!hasAmount || amount == 0) && containsMarker(lower, freePriceMarkers) {
+		price.Type = PriceFree
+		return price, true
+	}
+	if containsMarker(lower, onRequestPriceMarkers) {
+		price.Type = PriceOnRequest
+		return price, true
+	}
+	if containsMarker(lower, negotiablePriceMarkers) {
+		price.Type = PriceNegotiable
+	}
+
+	if !hasAmount {
+		// "VB" or "Bieden" without an amount asks for offers
+		if price.Type == PriceNegotiable {
+			price.Type = PriceOnRequest
+			return price, true
+		}
+		return Price{}, false
+	}
+	price.Amount = amount
+
+	return price, true
+}
+
+// containsMarker reports whether text contains one of the markers as a whole word
+func containsMarker(text string, markers []string) bool {
+	for _, marker := range markers {
+		for start := 0; ; {
+			index := strings.Index(text[start:], marker)
+			if index < 0 {
+				break
+			}
+			index += start
+			end := index + len(marker)
+			if (index == 0 || !isLetter(text[index-1])) && (end == len(text) || !isLetter(text[end])) {
+				return true
+			}
+			start = index + 1
+		}
+	}
+	return false
+}
+
+func isLetter(b byte) bool {
+	return b >= 'a' && b <= 'z'
+}
+
+// ParseAmount parses a decimal amount into cents. Both "1.250,50" and "1,250.50"
+// are understood: the last separator followed by one or two digits is the decimal
+// separator, any other separator groups thousands.
+func ParseAmount(value string) (int64, error) {
+	value = strings.Trim(value, " .,'")
+	value = strings.NewReplacer(" ", "", "'", "").Replace(value)
+	if value == "" {
+		return 0, fmt.Errorf("empty amount")
+	}
+
+	whole, fraction := value, ""
+	if index := strings.LastIndexAny(value, ".,"); index >= 0 {
+		if digits := len(value) - index - 1; digits == 1 || digits == 2 {
+			whole, fraction = value[:index], value[index+1:]
+		}
+	}
+	whole = strings.NewReplacer(".", "", ",", "").Replace(whole)
+	if len(fraction) == 1 {
+		fraction += "0"
+	}
+	if fraction == "" {
+		fraction = "00"
+	}
+
+	amount, err := strconv.ParseInt(whole+fraction, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount '%s': %w", value, err)
+	}
+	return amount, nil
+}
+
+// FormatPrice formats an amount in cents for display, like "€ 1250.00" or "GBP 12.50"
+func FormatPrice(amount int64, currency string) string {
+	symbol := currency
+	if currency == "EUR" {
+		symbol = "€"
+	}
+	return fmt.Sprintf("%s %d.%02d", symbol, amount/100, amount%100)
+}
@@ -0,0 +1,76 @@
+package siteclients
+
+import "testing"
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int64
+		wantErr bool
+	}{
+		{"150", 15000, false},
+		{"12.5", 1250, false},
+		{"12,50", 1250, false},
+		{"1.250", 125000, false},
+		{"1,250", 125000, false},
+		{"1.250,50", 125050, false},
+		{"1,250.50", 125050, false},
+		{"1 250,00", 125000, false},
+		{"1'250.00", 125000, false},
+		{"1.250.000", 125000000, false},
+		{"150.", 15000, false},
+		{"", 0, true},
+		{" .,", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseAmount(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseAmount(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseAmount(%q) = %d, want %d", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePrice(t *testing.T) {
+	tests := []struct {
+		text   string
+		want   Price
+		wantOK bool
+	}{
+		{"150 €", Price{15000, "EUR", PriceFixed}, true},
+		{"1.250,00 €", Price{125000, "EUR", PriceFixed}, true},
+		{"1.250 €", Price{125000, "EUR", PriceFixed}, true},
+		{"12.5", Price{1250, "EUR", PriceFixed}, true},
+		{"£1,250.50", Price{125050, "GBP", PriceFixed}, true},
+		{"US $1,250.50", Price{125050, "USD", PriceFixed}, true},
+		{"CHF 80.-", Price{8000, "CHF", PriceFixed}, true},
+		{"150 € VB", Price{15000, "EUR", PriceNegotiable}, true},
+		{"€ 95,- Bieden", Price{9500, "EUR", PriceNegotiable}, true},
+		{"VB", Price{0, "EUR", PriceOnRequest}, true},
+		{"Verhandlungsbasis", Price{0, "EUR", PriceOnRequest}, true},
+		{"Preis auf Anfrage", Price{0, "EUR", PriceOnRequest}, true},
+		{"Zu verschenken", Price{0, "EUR", PriceFree}, true},
+		{"Free", Price{0, "EUR", PriceFree}, true},
+		{"0 € gratis", Price{0, "EUR", PriceFree}, true},
+		{"50 € + free shipping", Price{5000, "EUR", PriceFixed}, true},
+		{"Carefree", Price{}, false},
+		{"Freeway", Price{}, false},
+		{"Lieferbar", Price{}, false},
+		{"", Price{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got, ok := ParsePrice(tt.text, "EUR")
+			if ok != tt.wantOK {
+				t.Fatalf("ParsePrice(%q) ok = %v, want %v", tt.text, ok, tt.wantOK)
+			}
+			if got != tt.want {
+				t.Errorf("ParsePrice(%q) = %+v, want %+v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
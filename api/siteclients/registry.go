@@ -0,0 +1,68 @@
+package siteclients
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ClientConfig holds the site information a factory needs to build a client.
+// Config is the raw client_config of the site, interpreted by the factory.
+type ClientConfig struct {
+	SiteID int
+	Name   string
+	URL    string
+	Config string
+}
+
+// Factory creates a SiteClient for the given site configuration
+type Factory func(config ClientConfig) (SiteClient, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Factory)
+)
+
+// Register makes a client factory available under the given client type key.
+// It is meant to be called from an init function in the package implementing
+// the client, and panics if the key is empty or already registered.
+func Register(clientType string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if clientType == "" {
+		panic("siteclients: Register called with empty client type")
+	}
+	if factory == nil {
+		panic("siteclients: Register factory is nil for " + clientType)
+	}
+	if _, exists := registry[clientType]; exists {
+		panic("siteclients: Register called twice for " + clientType)
+	}
+	registry[clientType] = factory
+}
+
+// NewClient creates a SiteClient using the factory registered under clientType
+func NewClient(clientType string, config ClientConfig) (SiteClient, error) {
+	registryMu.RLock()
+	factory, exists := registry[clientType]
+	registryMu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("no site client registered for client type '%s'", clientType)
+	}
+	return factory(config)
+}
+
+// RegisteredClientTypes returns the sorted list of registered client type keys
+func RegisteredClientTypes() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	types := make([]string, 0, len(registry))
+	for clientType := range registry {
+		types = append(types, clientType)
+	}
+	sort.Strings(types)
+	return types
+}
@@ -2,10 +2,8 @@ package siteclients
 
 import (
 	"context"
-	"encoding/base64"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
 	"strings"
@@ -42,6 +40,12 @@ type SchadeAutosClient struct {
 	siteID     int
 }
 
+func init() {
+	Register("schadeautos", func(config ClientConfig) (SiteClient, error) {
+		return NewSchadeAutosClient(config.SiteID), nil
+	})
+}
+
 // NewSchadeAutosClient creates a new SchadeAutos client
 func NewSchadeAutosClient(siteID int) *SchadeAutosClient {
 	return &SchadeAutosClient{
@@ -90,15 +94,85 @@ type stockPart struct {
 	Nos           []interface{} `json:"nos"`
 }
 
+// schadeAutosVehicle holds the widget codes SchadeAutos uses to select a vehicle.
+// Query is free text searched within the selected codes.
+type schadeAutosVehicle struct {
+	Make      string
+	BaseModel string
+	Model     string
+	Query     string
+}
+
+// schadeAutosVehicleCodes translates vehicles into SchadeAutos widget codes.
+// Vehicles without their own codes fall back to the make code plus a text query.
+var schadeAutosVehicleCodes = VehicleTable[schadeAutosVehicle]{
+	{VehicleKey: VehicleKey{Make: "Mitsubishi"}, Value: schadeAutosVehicle{Make: "A0001E2D"}},
+	{VehicleKey: VehicleKey{Make: "Mitsubishi", BaseModel: "Eclipse", YearFrom: 1995, YearTo: 1999}, Value: schadeAutosVehicle{Make: "A0001E2D", BaseModel: "A0001FHK", Model: "A0001FHL"}},
+}
+
+// vehiclesFor returns the vehicle selections to search for the given parameters
+func (c *SchadeAutosClient) vehiclesFor(params SearchParams) []schadeAutosVehicle {
+	vehicles := schadeAutosVehicleCodes.Match(params)
+	if len(vehicles) == 0 {
+		// Unknown make, search the whole catalogue by text
+		if keywords := params.Keywords(); keywords != "" {
+			return []schadeAutosVehicle{{Query: keywords}}
+		}
+		return nil
+	}
+
+	for i := range vehicles {
+		if vehicles[i].BaseModel != "" {
+			continue
+		}
+		// Only the make is known, narrow it down by the model names
+		vehicles[i].Query = strings.TrimSpace(params.BaseModel + " " + params.Model)
+	}
+	return vehicles
+}
+
+// schadeAutosBatchSize is the number of parts handed over at once while streaming.
+// The search returns everything in one response, but the images are fetched per part.
+const schadeAutosBatchSize = 50
+
 // FetchParts fetches parts from SchadeAutos based on search parameters
 func (c *SchadeAutosClient) FetchParts(ctx context.Context, params SearchParams) ([]Part, error) {
+	return CollectParts(ctx, c, params)
+}
+
+// StreamParts fetches parts from SchadeAutos, handing them over in batches as their images are fetched
+func (c *SchadeAutosClient) StreamParts(ctx context.Context, params SearchParams, handle PageHandler) error {
+	vehicles := c.vehiclesFor(params)
+	if len(vehicles) == 0 {
+		return fmt.Errorf("search params do not describe a vehicle")
+	}
+
+	stream := NewPartStream(params.Limit, handle)
+	for _, vehicle := range vehicles {
+		if err := c.searchParts(ctx, vehicle, params, stream); err != nil {
+			return err
+		}
+		if stream.Done() {
+			break
+		}
+	}
+
+	return nil
+}
+
+// searchParts runs a single search for the given vehicle selection
+func (c *SchadeAutosClient) searchParts(ctx context.Context, vehicle schadeAutosVehicle, params SearchParams, stream *PartStream) error {
 	// Build form data
 	formData := url.Values{}
 
-	formData.Set("widget[vehicleType]", "P")
-	formData.Set("widget[make]", "A0001E2D")
-	formData.Set("widget[baseModel]", "A0001FHK")
-	formData.Set("widget[model]", "A0001FHL")
+	vehicleType := params.VehicleType
+	if vehicleType == "" {
+		vehicleType = "P"
+	}
+	formData.Set("widget[vehicleType]", vehicleType)
+	formData.Set("widget[make]", vehicle.Make)
+	formData.Set("widget[baseModel]", vehicle.BaseModel)
+	formData.Set("widget[model]", vehicle.Model)
 	formData.Set("widget[type]", "")
 	formData.Set("widget[vehicle]", "")
 
@@ -119,7 +193,7 @@ func (c *SchadeAutosClient) FetchParts(ctx context.Context, params SearchParams)
 	formData.Set("widget[category]", "")
 	formData.Set("widget[part]", "")
 	formData.Set("widget[priceMax]", "")
-	formData.Set("widget[query]", "")
+	formData.Set("widget[query]", vehicle.Query)
 
 	// Set offset with default
 	offset := params.Offset
@@ -144,7 +218,7 @@ func (c *SchadeAutosClient) FetchParts(ctx context.Context, params SearchParams)
 	apiURL := fmt.Sprintf("%s/parts/eng/search.json", c.baseURL)
 	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, strings.NewReader(formData.Encode()))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return fmt.Errorf("failed to create request: %w", err)
 	}
 
 	// Set headers
@@ -159,26 +233,26 @@ func (c *SchadeAutosClient) FetchParts(ctx context.Context, params SearchParams)
 	// Execute request
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute request: %w", err)
+		return fmt.Errorf("failed to execute request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
 
 	// Parse response
 	var apiResponse schadeAutosResponse
 	if err := json.NewDecoder(resp.Body).Decode(&apiResponse); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+		return fmt.Errorf("failed to decode response: %w", err)
 	}
 
 	// Log response info
 	fmt.Printf("Response parsed. Limited: %v, Descr: %s, Parts count: %d\n",
 		apiResponse.Result.Limited, apiResponse.Result.Descr, len(apiResponse.Result.StockParts))
 
-	// Convert stock parts to Part structs
-	parts := make([]Part, 0, len(apiResponse.Result.StockParts))
+	// Convert stock parts to Part structs, handing them over in batches
+	batch := make([]Part, 0, schadeAutosBatchSize)
 	for partID, stockPart := range apiResponse.Result.StockParts {
 		part := Part{
 			ID:          partID,
@@ -190,22 +264,29 @@ func (c *SchadeAutosClient) FetchParts(ctx context.Context, params SearchParams)
 			Price:        "€ " + stockPart.Price,
 			CreationDate: *parseEnterDate(stockPart.EnterDate),
 		}
+		if price, ok := ParsePrice(stockPart.Price, "EUR"); ok {
+			part.SetPrice(price)
+		}
 
-		// Fetch and convert image to base64
+		// Images are downloaded by the parts service after the part is stored
 		if stockPart.Picture != "" {
-			imageBase64, fetchErr := c.fetchImageAsBase64(ctx, stockPart.Picture)
-			if fetchErr != nil {
-				// Log error but continue with other parts
-				fmt.Printf("Warning: failed to fetch image for part %s: %v\n", partID, fetchErr)
-			} else {
-				part.ImageBase64 = imageBase64
-			}
+			part.ImageURL = c.resolveImageURL(stockPart.Picture)
 		}
 
-		parts = append(parts, part)
+		batch = append(batch, part)
+		if len(batch) < schadeAutosBatchSize {
+			continue
+		}
+
+		done, err := stream.Emit(batch)
+		if err != nil || done {
+			return err
+		}
+		batch = batch[:0]
 	}
 
-	return parts, nil
+	_, err = stream.Emit(batch)
+	return err
 }
 
 // buildPartURL constructs the URL for a specific part
@@ -214,40 +295,12 @@ func (c *SchadeAutosClient) buildPartURL(partID string, part *stockPart) string
 	return fmt.Sprintf("%s/parts/eng/part/%s", c.baseURL, partID)
 }
 
-// fetchImageAsBase64 fetches an image from a URL and returns it as a base64 string
-func (c *SchadeAutosClient) fetchImageAsBase64(ctx context.Context, imageURL string) (string, error) {
-	// Handle relative URLs
+// resolveImageURL turns protocol-relative and relative picture URLs into absolute URLs
+func (c *SchadeAutosClient) resolveImageURL(imageURL string) string {
 	if strings.HasPrefix(imageURL, "//") {
-		imageURL = "https:" + imageURL
+		return "https:" + imageURL
 	} else if strings.HasPrefix(imageURL, "/") {
-		imageURL = c.baseURL + imageURL
-	}
-
-	req, err := http.NewRequestWithContext(ctx, "GET", imageURL, nil)
-	if err != nil {
-		return "", fmt.Errorf("failed to create image request: %w", err)
-	}
-
-	req.Header.Set("User-Agent", "Mozilla/5.0 (X11; Linux x86_64; rv:143.0) Gecko/20100101 Firefox/143.0")
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("failed to fetch image: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("unexpected status code for image: %d", resp.StatusCode)
-	}
-
-	// Read image data
-	imageData, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to read image data: %w", err)
+		return c.baseURL + imageURL
 	}
-
-	// Convert to base64
-	base64String := base64.StdEncoding.EncodeToString(imageData)
-
-	return base64String, nil
+	return imageURL
 }
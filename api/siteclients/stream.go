@@ -0,0 +1,96 @@
+package siteclients
+
+import "context"
+
+// PageHandler receives each batch of parts as soon as a client has fetched it.
+// Returning an error stops the fetch.
+type PageHandler func(parts []Part) error
+
+// StreamingSiteClient is implemented by clients that can hand over their results
+// page by page instead of returning everything at the end
+type StreamingSiteClient interface {
+	SiteClient
+
+	// StreamParts fetches parts like FetchParts, passing every page to handle
+	StreamParts(ctx context.Context, params SearchParams, handle PageHandler) error
+}
+
+// StreamParts streams the parts of any site client. Clients that do not implement
+// StreamingSiteClient are fetched in full and handed over as a single batch.
+func StreamParts(ctx context.Context, client SiteClient, params SearchParams, handle PageHandler) error {
+	if streamer, ok := client.(StreamingSiteClient); ok {
+		return streamer.StreamParts(ctx, params, handle)
+	}
+
+	parts, err := client.FetchParts(ctx, params)
+	if err != nil {
+		return err
+	}
+	if len(parts) == 0 {
+		return nil
+	}
+	return handle(parts)
+}
+
+// CollectParts runs a streaming client to completion and returns all its parts,
+// for implementing FetchParts on top of StreamParts
+func CollectParts(ctx context.Context, client StreamingSiteClient, params SearchParams) ([]Part, error) {
+	allParts := make([]Part, 0)
+	err := client.StreamParts(ctx, params, func(parts []Part) error {
+		allParts = append(allParts, parts...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return allParts, nil
+}
+
+// PartStream forwards pages to a PageHandler, dropping parts that were already
+// handed over by an earlier search and enforcing the overall limit
+type PartStream struct {
+	handle PageHandler
+	limit  int
+	seen   map[string]bool
+}
+
+// NewPartStream creates a PartStream; a limit of 0 means unlimited
+func NewPartStream(limit int, handle PageHandler) *PartStream {
+	return &PartStream{
+		handle: handle,
+		limit:  limit,
+		seen:   make(map[string]bool),
+	}
+}
+
+// Emit hands the unseen parts of a page to the handler and reports whether the limit has been reached
+func (s *PartStream) Emit(parts []Part) (bool, error) {
+	batch := make([]Part, 0, len(parts))
+	for _, part := range parts {
+		if s.Done() {
+			break
+		}
+		if s.seen[part.ID] {
+			continue
+		}
+		s.seen[part.ID] = true
+		batch = append(batch, part)
+	}
+
+	if len(batch) > 0 {
+		if err := s.handle(batch); err != nil {
+			return true, err
+		}
+	}
+	return s.Done(), nil
+}
+
+// Done reports whether the limit has been reached
+func (s *PartStream) Done() bool {
+	return s.limit > 0 && len(s.seen) >= s.limit
+}
+
+// Count returns the number of parts handed over so far
+func (s *PartStream) Count() int {
+	return len(s.seen)
+}
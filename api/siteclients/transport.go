@@ -0,0 +1,389 @@
+package siteclients
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HostLimit caps the request rate and the number of concurrent requests to a host
+type HostLimit struct {
+	RequestsPerSecond float64
+	MaxConcurrent     int
+}
+
+// TransportConfig configures the shared HTTP transport used by all site clients.
+// HostLimits are matched on the host or any parent domain, so "kleinanzeigen.de"
+// also covers "img.kleinanzeigen.de" and both share one budget.
+type TransportConfig struct {
+	MaxRetries     int
+	BaseDelay      time.Duration
+	MaxDelay       time.Duration
+	AttemptTimeout time.Duration
+	DefaultLimit   HostLimit
+	HostLimits     map[string]HostLimit
+}
+
+// DefaultTransportConfig returns the transport configuration used when nothing is configured
+func DefaultTransportConfig() TransportConfig {
+	return TransportConfig{
+		MaxRetries:     3,
+		BaseDelay:      500 * time.Millisecond,
+		MaxDelay:       30 * time.Second,
+		AttemptTimeout: 30 * time.Second,
+		DefaultLimit:   HostLimit{RequestsPerSecond: 5, MaxConcurrent: 4},
+		HostLimits: map[string]HostLimit{
+			"kleinanzeigen.de": {RequestsPerSecond: 2, MaxConcurrent: 2},
+			"schadeautos.nl":   {RequestsPerSecond: 4, MaxConcurrent: 4},
+		},
+	}
+}
+
+// TransportConfigFromEnv returns the default configuration overridden by environment variables:
+//
+//	HTTP_MAX_RETRIES=3
+//	HTTP_RETRY_BASE_DELAY=500ms
+//	HTTP_RETRY_MAX_DELAY=30s
+//	HTTP_RATE_LIMITS=kleinanzeigen.de=2,ebay.com=10     (requests per second per host)
+//	HTTP_CONCURRENCY_LIMITS=schadeautos.nl=4            (concurrent requests per host)
+func TransportConfigFromEnv() TransportConfig {
+	config := DefaultTransportConfig()
+
+	if value, err := strconv.Atoi(os.Getenv("HTTP_MAX_RETRIES")); err == nil && value >= 0 {
+		config.MaxRetries = value
+	}
+	if value, err := time.ParseDuration(os.Getenv("HTTP_RETRY_BASE_DELAY")); err == nil && value > 0 {
+		config.BaseDelay = value
+	}
+	if value, err := time.ParseDuration(os.Getenv("HTTP_RETRY_MAX_DELAY")); err == nil && value > 0 {
+		config.MaxDelay = value
+	}
+
+	for host, value := range parseHostValues(os.Getenv("HTTP_RATE_LIMITS")) {
+		rps, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			log.Printf("[HTTP] WARNING: Invalid rate limit '%s' for host %s", value, host)
+			continue
+		}
+		limit := config.limitFor(host)
+		limit.RequestsPerSecond = rps
+		config.HostLimits[host] = limit
+	}
+
+	for host, value := range parseHostValues(os.Getenv("HTTP_CONCURRENCY_LIMITS")) {
+		maxConcurrent, err := strconv.Atoi(value)
+		if err != nil {
+			log.Printf("[HTTP] WARNING: Invalid concurrency limit '%s' for host %s", value, host)
+			continue
+		}
+		limit := config.limitFor(host)
+		limit.MaxConcurrent = maxConcurrent
+		config.HostLimits[host] = limit
+	}
+
+	return config
+}
+
+// parseHostValues parses a "host=value,host=value" list
+func parseHostValues(list string) map[string]string {
+	values := make(map[string]string)
+	for _, entry := range strings.Split(list, ",") {
+		host, value, found := strings.Cut(strings.TrimSpace(entry), "=")
+		if !found || host == "" {
+			continue
+		}
+		values[strings.ToLower(strings.TrimSpace(host))] = strings.TrimSpace(value)
+	}
+	return values
+}
+
+// limitKey returns the HostLimits key that applies to a host, or the host itself
+func (c TransportConfig) limitKey(host string) string {
+	host = strings.ToLower(host)
+	for candidate := host; candidate != ""; {
+		if _, exists := c.HostLimits[candidate]; exists {
+			return candidate
+		}
+		_, parent, found := strings.Cut(candidate, ".")
+		if !found {
+			break
+		}
+		candidate = parent
+	}
+	return host
+}
+
+// limitFor returns the limit that applies to a host
+func (c TransportConfig) limitFor(host string) HostLimit {
+	if limit, exists := c.HostLimits[c.limitKey(host)]; exists {
+		return limit
+	}
+	return c.DefaultLimit
+}
+
+// hostState tracks the rate limit and concurrency budget of a single host
+type hostState struct {
+	mu       sync.Mutex
+	next     time.Time
+	interval time.Duration
+	slots    chan struct{}
+}
+
+func newHostState(limit HostLimit) *hostState {
+	state := &hostState{}
+	if limit.RequestsPerSecond > 0 {
+		state.interval = time.Duration(float64(time.Second) / limit.RequestsPerSecond)
+	}
+	if limit.MaxConcurrent > 0 {
+		state.slots = make(chan struct{}, limit.MaxConcurrent)
+	}
+	return state
+}
+
+// acquire waits for a concurrency slot and the next rate limit slot
+func (h *hostState) acquire(ctx context.Context) error {
+	if h.slots != nil {
+		select {
+		case h.slots <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if h.interval > 0 {
+		h.mu.Lock()
+		now := time.Now()
+		slot := h.next
+		if slot.Before(now) {
+			slot = now
+		}
+		h.next = slot.Add(h.interval)
+		h.mu.Unlock()
+
+		if err := sleepContext(ctx, slot.Sub(now)); err != nil {
+			h.release()
+			return err
+		}
+	}
+	return nil
+}
+
+// release frees the concurrency slot taken by acquire
+func (h *hostState) release() {
+	if h.slots != nil {
+		<-h.slots
+	}
+}
+
+// ResilientTransport is an http.RoundTripper that rate limits requests per host,
+// caps concurrent requests per host and retries transient failures with
+// exponential backoff, jitter and Retry-After support
+type ResilientTransport struct {
+	base http.RoundTripper
+
+	mu     sync.Mutex
+	config TransportConfig
+	hosts  map[string]*hostState
+}
+
+// NewResilientTransport creates a transport wrapping base with the given configuration
+func NewResilientTransport(base http.RoundTripper, config TransportConfig) *ResilientTransport {
+	return &ResilientTransport{
+		base:   base,
+		config: config,
+		hosts:  make(map[string]*hostState),
+	}
+}
+
+// Configure replaces the configuration and resets the per-host state
+func (t *ResilientTransport) Configure(config TransportConfig) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.config = config
+	t.hosts = make(map[string]*hostState)
+}
+
+// stateFor returns the configuration and the shared state for a host
+func (t *ResilientTransport) stateFor(host string) (TransportConfig, *hostState) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := t.config.limitKey(host)
+	state, exists := t.hosts[key]
+	if !exists {
+		state = newHostState(t.config.limitFor(host))
+		t.hosts[key] = state
+	}
+	return t.config, state
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *ResilientTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	config, host := t.stateFor(req.URL.Hostname())
+	ctx := req.Context()
+
+	// Requests with a body can only be retried if the body can be recreated
+	canRetry := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+
+	for attempt := 0; ; attempt++ {
+		if err := host.acquire(ctx); err != nil {
+			return nil, err
+		}
+
+		attemptReq, cancel, err := t.prepareAttempt(req, config, attempt)
+		if err != nil {
+			host.release()
+			return nil, err
+		}
+
+		resp, err := t.base.RoundTrip(attemptReq)
+		retryable := isRetryable(resp, err)
+		if !retryable || !canRetry || attempt >= config.MaxRetries || ctx.Err() != nil {
+			if err != nil {
+				cancel()
+				host.release()
+				return nil, err
+			}
+			// Keep the attempt context and the host slot until the body is closed
+			resp.Body = &releasingBody{ReadCloser: resp.Body, release: func() {
+				cancel()
+				host.release()
+			}}
+			return resp, nil
+		}
+
+		delay := backoffDelay(config, attempt)
+		reason := ""
+		if err != nil {
+			reason = err.Error()
+		} else {
+			reason = resp.Status
+			if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+				delay = min(retryAfter, config.MaxDelay)
+			}
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+			resp.Body.Close()
+		}
+		cancel()
+		host.release()
+
+		log.Printf("[HTTP] Retrying %s %s in %v (attempt %d/%d): %s",
+			req.Method, req.URL.Host+req.URL.Path, delay.Round(time.Millisecond), attempt+1, config.MaxRetries, reason)
+		if err := sleepContext(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// prepareAttempt clones the request for a single attempt with its own timeout and a fresh body
+func (t *ResilientTransport) prepareAttempt(req *http.Request, config TransportConfig, attempt int) (*http.Request, context.CancelFunc, error) {
+	ctx, cancel := context.Background(), context.CancelFunc(func() {})
+	if config.AttemptTimeout > 0 {
+		ctx, cancel = context.WithTimeout(req.Context(), config.AttemptTimeout)
+	} else {
+		ctx, cancel = context.WithCancel(req.Context())
+	}
+
+	attemptReq := req.Clone(ctx)
+	if attempt > 0 && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			cancel()
+			return nil, nil, fmt.Errorf("failed to recreate request body: %w", err)
+		}
+		attemptReq.Body = body
+	}
+	return attemptReq, cancel, nil
+}
+
+// isRetryable reports whether a response or error is worth retrying
+func isRetryable(resp *http.Response, err error) bool {
+	if err != nil {
+		// A cancelled or expired caller context is final, an attempt timeout is not
+		return !errors.Is(err, context.Canceled)
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// backoffDelay returns the exponential backoff for an attempt with jitter between half and the full delay
+func backoffDelay(config TransportConfig, attempt int) time.Duration {
+	delay := config.BaseDelay << attempt
+	// Shifting back detects delays that overflowed after many attempts
+	if delay <= 0 || delay>>attempt != config.BaseDelay || delay > config.MaxDelay {
+		delay = config.MaxDelay
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0), true
+	}
+	return 0, false
+}
+
+// sleepContext sleeps for d or until the context is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// releasingBody runs release once when the response body is closed
+type releasingBody struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
+
+// sharedTransport is used by every client created through CreateHTTPClient, so
+// rate limits and concurrency caps hold across clients hitting the same host
+var sharedTransport = NewResilientTransport(&http.Transport{
+	Proxy:               http.ProxyFromEnvironment,
+	TLSClientConfig:     &tls.Config{InsecureSkipVerify: true},
+	MaxIdleConnsPerHost: 8,
+	IdleConnTimeout:     90 * time.Second,
+}, DefaultTransportConfig())
+
+// ConfigureHTTP replaces the configuration of the shared transport
+func ConfigureHTTP(config TransportConfig) {
+	sharedTransport.Configure(config)
+}
@@ -0,0 +1,229 @@
+package siteclients
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testTransportConfig limits the test server host to maxConcurrent requests without
+// rate limit or retries
+func testTransportConfig(maxConcurrent int) TransportConfig {
+	return TransportConfig{
+		MaxDelay:     time.Second,
+		DefaultLimit: HostLimit{MaxConcurrent: maxConcurrent},
+	}
+}
+
+func TestResilientTransportPagesBeyondMaxConcurrent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("page"))
+	}))
+	defer server.Close()
+
+	const maxConcurrent = 2
+	client := &http.Client{Transport: NewResilientTransport(http.DefaultTransport, testTransportConfig(maxConcurrent))}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	// Every page closes its body before the next one is requested
+	for page := 0; page < 3*maxConcurrent; page++ {
+		req, _ := http.NewRequestWithContext(ctx, "GET", server.URL, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("page %d: %v", page, err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+}
+
+func TestResilientTransportHoldsSlotUntilBodyClosed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("page"))
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: NewResilientTransport(http.DefaultTransport, testTransportConfig(1))}
+
+	first, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL, nil)
+	if _, err := client.Do(req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("request while the only slot is held: got %v, want deadline exceeded", err)
+	}
+
+	first.Body.Close()
+	second, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("request after the body was closed: %v", err)
+	}
+	second.Body.Close()
+}
+
+// retryTransportConfig retries up to three times with millisecond backoff
+func retryTransportConfig() TransportConfig {
+	return TransportConfig{
+		MaxRetries: 3,
+		BaseDelay:  time.Millisecond,
+		MaxDelay:   50 * time.Millisecond,
+	}
+}
+
+func TestResilientTransportRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		statuses     []int // returned by the server in turn, the last one repeats
+		retryAfter   string
+		method       string
+		wantStatus   int
+		wantAttempts int
+	}{
+		{"success", []int{200}, "", "GET", 200, 1},
+		{"not found is final", []int{404}, "", "GET", 404, 1},
+		{"recovers after 503", []int{503, 503, 200}, "", "GET", 200, 3},
+		{"recovers after 429 with Retry-After", []int{429, 200}, "0", "GET", 200, 2},
+		{"Retry-After capped by MaxDelay", []int{429, 200}, "3600", "GET", 200, 2},
+		{"gives up after MaxRetries", []int{502}, "", "GET", 502, 4},
+		{"retries POST with its body", []int{500, 200}, "", "POST", 200, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				if r.Method == "POST" && string(body) != "query" {
+					t.Errorf("attempt %d got body %q, want %q", attempts+1, body, "query")
+				}
+				status := tt.statuses[min(attempts, len(tt.statuses)-1)]
+				attempts++
+				if tt.retryAfter != "" {
+					w.Header().Set("Retry-After", tt.retryAfter)
+				}
+				w.WriteHeader(status)
+			}))
+			defer server.Close()
+
+			client := &http.Client{Transport: NewResilientTransport(http.DefaultTransport, retryTransportConfig())}
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+
+			var body io.Reader
+			if tt.method == "POST" {
+				body = strings.NewReader("query")
+			}
+			req, _ := http.NewRequestWithContext(ctx, tt.method, server.URL, body)
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", attempts, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		err    error
+		want   bool
+	}{
+		{"ok", 200, nil, false},
+		{"not found", 404, nil, false},
+		{"forbidden", 403, nil, false},
+		{"too many requests", 429, nil, true},
+		{"internal server error", 500, nil, true},
+		{"bad gateway", 502, nil, true},
+		{"service unavailable", 503, nil, true},
+		{"gateway timeout", 504, nil, true},
+		{"not implemented", 501, nil, false},
+		{"connection error", 0, errors.New("connection reset by peer"), true},
+		{"attempt timeout", 0, context.DeadlineExceeded, true},
+		{"cancelled", 0, context.Canceled, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resp *http.Response
+			if tt.err == nil {
+				resp = &http.Response{StatusCode: tt.status}
+			}
+			if got := isRetryable(resp, tt.err); got != tt.want {
+				t.Errorf("isRetryable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{"", 0, false},
+		{"0", 0, true},
+		{"120", 2 * time.Minute, true},
+		{" 5 ", 5 * time.Second, true},
+		{"-1", 0, false},
+		{"1.5", 0, false},
+		{"soon", 0, false},
+		{"Wed, 21 Oct 2015 07:28:00 GMT", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, ok := parseRetryAfter(tt.value)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("parseRetryAfter(%q) = %v, %v, want %v, %v", tt.value, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+
+	// A date in the future waits until then
+	future := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	got, ok := parseRetryAfter(future)
+	if !ok || got < 59*time.Minute || got > time.Hour {
+		t.Errorf("parseRetryAfter(%q) = %v, %v, want about an hour", future, got, ok)
+	}
+}
+
+func TestBackoffDelay(t *testing.T) {
+	config := TransportConfig{BaseDelay: 500 * time.Millisecond, MaxDelay: 30 * time.Second}
+	tests := []struct {
+		attempt  int
+		min, max time.Duration
+	}{
+		{0, 250 * time.Millisecond, 500 * time.Millisecond},
+		{1, 500 * time.Millisecond, time.Second},
+		{3, 2 * time.Second, 4 * time.Second},
+		{6, 15 * time.Second, 30 * time.Second},
+		{10, 15 * time.Second, 30 * time.Second},
+		{36, 15 * time.Second, 30 * time.Second},
+		{64, 15 * time.Second, 30 * time.Second},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			if got := backoffDelay(config, tt.attempt); got < tt.min || got > tt.max {
+				t.Fatalf("backoffDelay(attempt %d) = %v, want between %v and %v", tt.attempt, got, tt.min, tt.max)
+			}
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package siteclients
+
+import "strings"
+
+// VehicleKey identifies the vehicles a translation entry applies to.
+// Empty fields and zero years act as wildcards.
+type VehicleKey struct {
+	Make      string `yaml:"make" json:"make"`
+	BaseModel string `yaml:"base_model" json:"base_model"`
+	Model     string `yaml:"model" json:"model"`
+	YearFrom  int    `yaml:"year_from" json:"year_from"`
+	YearTo    int    `yaml:"year_to" json:"year_to"`
+}
+
+// VehicleMapping maps a vehicle to a site-specific value, like widget codes or keywords
+type VehicleMapping[T any] struct {
+	VehicleKey
+	Value T
+}
+
+// VehicleTable is a per-site translation table from SearchParams to site queries
+type VehicleTable[T any] []VehicleMapping[T]
+
+// Match returns the values of the entries that best match the search parameters, in
+// table order. Entries rank by how many of the requested vehicle fields they pin
+// down, so a search that leaves fields empty gets every entry it matches instead of
+// the most detailed one: a make-only search gets all models of the make. Entries
+// that pin down nothing beyond the request, like a make-level entry for a make-only
+// search, cover the more detailed ones and are returned alone.
+func (t VehicleTable[T]) Match(params SearchParams) []T {
+	var matches []VehicleMapping[T]
+	bestSpecificity := -1
+
+	for _, mapping := range t {
+		if !mapping.matches(params) {
+			continue
+		}
+
+		specificity := mapping.specificity(params)
+		if specificity > bestSpecificity {
+			bestSpecificity = specificity
+			matches = matches[:0]
+		}
+		if specificity == bestSpecificity {
+			matches = append(matches, mapping)
+		}
+	}
+
+	covering := make([]T, 0, len(matches))
+	for _, mapping := range matches {
+		if mapping.fields() == bestSpecificity {
+			covering = append(covering, mapping.Value)
+		}
+	}
+	if len(covering) > 0 {
+		return covering
+	}
+
+	values := make([]T, len(matches))
+	for i, mapping := range matches {
+		values[i] = mapping.Value
+	}
+	return values
+}
+
+// matches reports whether the key applies to the search parameters
+func (k VehicleKey) matches(params SearchParams) bool {
+	if !fieldMatches(k.Make, params.Make) ||
+		!fieldMatches(k.BaseModel, params.BaseModel) ||
+		!fieldMatches(k.Model, params.Model) {
+		return false
+	}
+
+	// Year ranges only need to overlap
+	if k.YearFrom != 0 && params.YearTo != 0 && params.YearTo < k.YearFrom {
+		return false
+	}
+	if k.YearTo != 0 && params.YearFrom != 0 && params.YearFrom > k.YearTo {
+		return false
+	}
+
+	return true
+}
+
+// specificity counts the requested vehicle fields the key pins down; fields the
+// search leaves empty do not count, whatever the key says about them
+func (k VehicleKey) specificity(params SearchParams) int {
+	specificity := 0
+	keyFields := []string{k.Make, k.BaseModel, k.Model}
+	for i, param := range []string{params.Make, params.BaseModel, params.Model} {
+		if keyFields[i] != "" && param != "" {
+			specificity++
+		}
+	}
+	return specificity
+}
+
+// fields counts the vehicle fields the key pins down
+func (k VehicleKey) fields() int {
+	fields := 0
+	for _, field := range []string{k.Make, k.BaseModel, k.Model} {
+		if field != "" {
+			fields++
+		}
+	}
+	return fields
+}
+
+func fieldMatches(keyValue, paramValue string) bool {
+	return keyValue == "" || paramValue == "" || strings.EqualFold(keyValue, paramValue)
+}
+
+// Keywords returns the vehicle described by the search parameters as free text,
+// used by clients when their translation table has no entry for it
+func (p SearchParams) Keywords() string {
+	fields := make([]string, 0, 3)
+	for _, field := range []string{p.Make, p.BaseModel, p.Model} {
+		if field = strings.TrimSpace(field); field != "" {
+			fields = append(fields, field)
+		}
+	}
+	return strings.Join(fields, " ")
+}
+
+// MatchKeywords returns the keyword searches for the search parameters, falling back
+// to the free text of the parameters when the table has no matching entry
+func MatchKeywords(table VehicleTable[string], params SearchParams) []string {
+	if keywords := table.Match(params); len(keywords) > 0 {
+		return keywords
+	}
+	if keywords := params.Keywords(); keywords != "" {
+		return []string{keywords}
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package siteclients
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestVehicleTableMatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		params SearchParams
+		want   []string
+	}{
+		{
+			name:   "empty query",
+			params: SearchParams{},
+			want: []string{
+				"(Mitsubishi Eclipse 1g, D27A)", "(Mitsubishi Eclipse 2g, D32A)",
+				"Eagle Talon", "Plymouth Laser", "(Galant VR-4, Galant VR4)",
+			},
+		},
+		{
+			name:   "fetch-all defaults",
+			params: SearchParams{YearFrom: 1960, YearTo: 2025},
+			want: []string{
+				"(Mitsubishi Eclipse 1g, D27A)", "(Mitsubishi Eclipse 2g, D32A)",
+				"Eagle Talon", "Plymouth Laser", "(Galant VR-4, Galant VR4)",
+			},
+		},
+		{
+			name:   "make only",
+			params: SearchParams{Make: "Mitsubishi"},
+			want: []string{
+				"(Mitsubishi Eclipse 1g, D27A)", "(Mitsubishi Eclipse 2g, D32A)", "(Galant VR-4, Galant VR4)",
+			},
+		},
+		{
+			name:   "make only, case insensitive",
+			params: SearchParams{Make: "eagle"},
+			want:   []string{"Eagle Talon"},
+		},
+		{
+			name:   "base model across generations",
+			params: SearchParams{Make: "Mitsubishi", BaseModel: "Eclipse"},
+			want:   []string{"(Mitsubishi Eclipse 1g, D27A)", "(Mitsubishi Eclipse 2g, D32A)"},
+		},
+		{
+			name:   "base model in one generation",
+			params: SearchParams{Make: "Mitsubishi", BaseModel: "Eclipse", YearFrom: 1996, YearTo: 1998},
+			want:   []string{"(Mitsubishi Eclipse 2g, D32A)"},
+		},
+		{
+			name:   "full model",
+			params: SearchParams{Make: "Mitsubishi", BaseModel: "Galant", Model: "VR-4", YearFrom: 1990, YearTo: 1991},
+			want:   []string{"(Galant VR-4, Galant VR4)"},
+		},
+		{
+			name:   "years outside every entry",
+			params: SearchParams{Make: "Mitsubishi", BaseModel: "Eclipse", YearFrom: 2005, YearTo: 2010},
+			want:   nil,
+		},
+		{
+			name:   "unknown make",
+			params: SearchParams{Make: "Toyota"},
+			want:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ebayVehicleKeywords.Match(tt.params)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Match(%+v) = %q, want %q", tt.params, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVehicleTableMatchCoveringEntry(t *testing.T) {
+	mitsubishi := schadeAutosVehicle{Make: "A0001E2D"}
+	eclipse := schadeAutosVehicle{Make: "A0001E2D", BaseModel: "A0001FHK", Model: "A0001FHL"}
+
+	tests := []struct {
+		name   string
+		params SearchParams
+		want   []schadeAutosVehicle
+	}{
+		{
+			name:   "empty query",
+			params: SearchParams{YearFrom: 1960, YearTo: 2025},
+			want:   []schadeAutosVehicle{mitsubishi, eclipse},
+		},
+		{
+			name:   "make only gets the make-level entry",
+			params: SearchParams{Make: "Mitsubishi", YearFrom: 1960, YearTo: 2025},
+			want:   []schadeAutosVehicle{mitsubishi},
+		},
+		{
+			name:   "full model gets its codes",
+			params: SearchParams{Make: "Mitsubishi", BaseModel: "Eclipse", Model: "GSX", YearFrom: 1997, YearTo: 1997},
+			want:   []schadeAutosVehicle{eclipse},
+		},
+		{
+			name:   "model without codes falls back to the make",
+			params: SearchParams{Make: "Mitsubishi", BaseModel: "Galant"},
+			want:   []schadeAutosVehicle{mitsubishi},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := schadeAutosVehicleCodes.Match(tt.params)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Match(%+v) = %+v, want %+v", tt.params, got, tt.want)
+			}
+		})
+	}
+}
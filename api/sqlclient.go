@@ -1,12 +1,19 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
+	"unicode"
 
+	"dsmpartsfinder-api/categories"
+	"dsmpartsfinder-api/duplicates"
+	"dsmpartsfinder-api/images"
 	. "dsmpartsfinder-api/models"
 
 	_ "github.com/glebarez/go-sqlite"
@@ -14,13 +21,15 @@ import (
 
 // SQLClient wraps database operations for the DSM Parts Finder
 type SQLClient struct {
-	db *sql.DB
+	db              *sql.DB
+	displayCurrency string
 }
 
+// GetTotalPartsCount counts the parts that are not gone from their site
 func (c *SQLClient) GetTotalPartsCount() (int, error) {
 	var count int
-	query := "SELECT COUNT(*) FROM parts"
-	err := c.db.QueryRow(query).Scan(&count)
+	query := "SELECT COUNT(*) FROM parts WHERE status != ?"
+	err := c.db.QueryRow(query, PartStatusGone).Scan(&count)
 	if err != nil {
 		logError("Failed to get total parts count", err)
 		return 0, err
@@ -28,49 +37,132 @@ func (c *SQLClient) GetTotalPartsCount() (int, error) {
 	return count, nil
 }
 
-func (c *SQLClient) GetFilteredPartsCount(typeFilter string, siteIDs []int, newerThan time.Time, search string) (int, error) {
+func (c *SQLClient) GetFilteredPartsCount(filter PartsFilter) (int, error) {
+	where, params := buildPartsFilter(filter)
+
+	var count int
+	err := c.db.QueryRow("SELECT COUNT(*) FROM parts WHERE 1=1"+where, params...).Scan(&count)
+	if err != nil {
+		logError("Failed to get filtered parts count", err)
+		return 0, err
+	}
+	return count, nil
+}
+
+// GetNewestPartTime returns the created_at of the newest part matching the filter,
+// or the zero time when no part matches
+func (c *SQLClient) GetNewestPartTime(filter PartsFilter) (time.Time, error) {
+	where, params := buildPartsFilter(filter)
+
+	var newest time.Time
+	err := c.db.QueryRow("SELECT created_at FROM parts WHERE 1=1"+where+" ORDER BY created_at DESC LIMIT 1", params...).Scan(&newest)
+	if err == sql.ErrNoRows {
+		return time.Time{}, nil
+	} else if err != nil {
+		logError("Failed to get newest part time", err)
+		return time.Time{}, err
+	}
+	return newest, nil
+}
+
+// buildPartsFilter builds the WHERE conditions for a parts filter, to be appended after "WHERE 1=1"
+func buildPartsFilter(filter PartsFilter) (string, []interface{}) {
 	queryBuilder := strings.Builder{}
 	params := make([]interface{}, 0)
 
-	queryBuilder.WriteString("SELECT COUNT(*) FROM parts WHERE 1=1")
+	if !filter.IncludeGone {
+		queryBuilder.WriteString(" AND status != ?")
+		params = append(params, PartStatusGone)
+	}
 
-	if typeFilter != "" {
+	if filter.TypeFilter != "" {
 		queryBuilder.WriteString(" AND type_name = ?")
-		params = append(params, typeFilter)
+		params = append(params, filter.TypeFilter)
 	}
 
-	if len(siteIDs) > 0 {
-		placeholders := make([]string, len(siteIDs))
-		for i := range siteIDs {
+	if len(filter.SiteIDs) > 0 {
+		placeholders := make([]string, len(filter.SiteIDs))
+		for i := range filter.SiteIDs {
 			placeholders[i] = "?"
-			params = append(params, siteIDs[i])
+			params = append(params, filter.SiteIDs[i])
 		}
 		queryBuilder.WriteString(" AND site_id IN (" + strings.Join(placeholders, ",") + ")")
 	}
 
-	if !newerThan.IsZero() {
+	if !filter.NewerThan.IsZero() {
 		queryBuilder.WriteString(" AND creation_date > ?")
-		params = append(params, newerThan)
+		params = append(params, filter.NewerThan)
 	}
 
-	if search != "" {
-		queryBuilder.WriteString(" AND (name LIKE ? OR description LIKE ? OR type_name LIKE ?)")
-		searchPattern := "%" + search + "%"
-		params = append(params, searchPattern, searchPattern, searchPattern)
+	if query := ftsQuery(filter.Search); query != "" {
+		queryBuilder.WriteString(" AND id IN (SELECT rowid FROM parts_fts WHERE parts_fts MATCH ?)")
+		params = append(params, query)
 	}
 
-	var count int
-	err := c.db.QueryRow(queryBuilder.String(), params...).Scan(&count)
-	if err != nil {
-		logError("Failed to get filtered parts count", err)
-		return 0, err
+	if filter.PartNumber != "" {
+		queryBuilder.WriteString(" AND id IN (SELECT part_id FROM part_numbers WHERE number = ?)")
+		params = append(params, filter.PartNumber)
 	}
-	return count, nil
+
+	if filter.ProfileID != 0 {
+		queryBuilder.WriteString(" AND id IN (SELECT part_id FROM part_profiles WHERE profile_id = ?)")
+		params = append(params, filter.ProfileID)
+	}
+
+	if filter.MinPrice != nil {
+		queryBuilder.WriteString(" AND price_display_amount >= ?")
+		params = append(params, *filter.MinPrice)
+	}
+
+	if filter.MaxPrice != nil {
+		queryBuilder.WriteString(" AND price_display_amount <= ?")
+		params = append(params, *filter.MaxPrice)
+	}
+
+	if filter.PriceDropped {
+		queryBuilder.WriteString(" AND " + highestPriceSQL + " > price_amount")
+	}
+
+	// Of every cluster of duplicates only the oldest part matching the other filters is kept
+	if filter.CollapseDuplicates {
+		inner := filter
+		inner.CollapseDuplicates = false
+		innerQuery, innerParams := buildPartsFilter(inner)
+		queryBuilder.WriteString(" AND (cluster_id IS NULL OR id IN (SELECT MIN(id) FROM parts WHERE cluster_id IS NOT NULL" +
+			innerQuery + " GROUP BY cluster_id))")
+		params = append(params, innerParams...)
+	}
+
+	return queryBuilder.String(), params
 }
 
+// ftsQuery turns a search text into an FTS5 query that matches parts containing
+// every word, in any order, as a word or the start of one. Words are split like
+// the unicode61 tokenizer does, which also keeps FTS5 syntax out of the query.
+// It returns "" when the text holds no words.
+func ftsQuery(search string) string {
+	words := strings.FieldsFunc(search, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	terms := make([]string, len(words))
+	for i, word := range words {
+		terms[i] = `"` + word + `"*`
+	}
+	return strings.Join(terms, " ")
+}
+
+// ftsRankSQL ranks the parts matching the full-text query given as parameter by
+// bm25, weighing matches in the name highest and in the description lowest
+const ftsRankSQL = `SELECT rowid AS match_id, bm25(parts_fts, 10.0, 1.0, 5.0) AS match_rank FROM parts_fts WHERE parts_fts MATCH ?`
+
 // NewSQLClient creates and initializes a new SQLClient
 func NewSQLClient(dbPath string) (*SQLClient, error) {
-	db, err := sql.Open("sqlite", dbPath)
+	// Enable foreign keys on every pooled connection so ON DELETE CASCADE applies
+	separator := "?"
+	if strings.Contains(dbPath, "?") {
+		separator = "&"
+	}
+	db, err := sql.Open("sqlite", dbPath+separator+"_pragma=foreign_keys(1)")
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -85,7 +177,8 @@ func NewSQLClient(dbPath string) (*SQLClient, error) {
 
 	log.Printf("Connected to SQLite database successfully. Version: %s", sqliteVersion)
 
-	return &SQLClient{db: db}, nil
+	// Prices are shown in euros until told otherwise, matching the migrated data
+	return &SQLClient{db: db, displayCurrency: "EUR"}, nil
 }
 
 // Close closes the database connection
@@ -103,9 +196,24 @@ func logSuccess(message string) {
 	log.Printf("SUCCESS: %s", message)
 }
 
+// siteColumns is the column list shared by every query that returns sites
+const siteColumns = `id, site_url, site_name, client_type, client_config, missing_after_hours, gone_after_hours, purge_after_days,
+	schedule, schedule_jitter_seconds, quiet_hours_start, quiet_hours_end`
+
+// scanSite scans a row selected with siteColumns into a Site
+func scanSite(scanner rowScanner) (Site, error) {
+	var site Site
+	err := scanner.Scan(
+		&site.ID, &site.URL, &site.Name, &site.ClientType, &site.ClientConfig,
+		&site.MissingAfterHours, &site.GoneAfterHours, &site.PurgeAfterDays,
+		&site.Schedule, &site.ScheduleJitterSeconds, &site.QuietHoursStart, &site.QuietHoursEnd,
+	)
+	return site, err
+}
+
 // GetAllSites retrieves all sites from the database
 func (c *SQLClient) GetAllSites() ([]Site, error) {
-	rows, err := c.db.Query("SELECT id, site_url, site_name FROM sites")
+	rows, err := c.db.Query("SELECT " + siteColumns + " FROM sites")
 	if err != nil {
 		logError("Failed to query sites", err)
 		return nil, err
@@ -114,8 +222,7 @@ func (c *SQLClient) GetAllSites() ([]Site, error) {
 
 	var sites []Site
 	for rows.Next() {
-		var site Site
-		err := rows.Scan(&site.ID, &site.URL, &site.Name)
+		site, err := scanSite(rows)
 		if err != nil {
 			logError("Failed to scan site data", err)
 			return nil, err
@@ -134,9 +241,7 @@ func (c *SQLClient) GetAllSites() ([]Site, error) {
 
 // GetSiteByID retrieves a single site by its ID
 func (c *SQLClient) GetSiteByID(id int) (*Site, error) {
-	var site Site
-	err := c.db.QueryRow("SELECT id, site_url, site_name FROM sites WHERE id = ?", id).
-		Scan(&site.ID, &site.URL, &site.Name)
+	site, err := scanSite(c.db.QueryRow("SELECT "+siteColumns+" FROM sites WHERE id = ?", id))
 
 	if err == sql.ErrNoRows {
 		return nil, sql.ErrNoRows
@@ -149,56 +254,57 @@ func (c *SQLClient) GetSiteByID(id int) (*Site, error) {
 	return &site, nil
 }
 
-// CreateSite creates a new site in the database
-func (c *SQLClient) CreateSite(name, url string) (*Site, error) {
-	result, err := c.db.Exec("INSERT INTO sites (site_url, site_name) VALUES (?, ?)", url, name)
+// CreateSite creates a new site in the database and returns it as stored, with the
+// defaults of the columns not given
+func (c *SQLClient) CreateSite(name, url, clientType, clientConfig string) (*Site, error) {
+	site, err := scanSite(c.db.QueryRow(`INSERT INTO sites (site_url, site_name, client_type, client_config)
+		VALUES (?, ?, ?, ?)
+		RETURNING `+siteColumns,
+		url, name, clientType, clientConfig))
 	if err != nil {
 		logError("Failed to create site", err)
 		return nil, err
 	}
 
-	id, err := result.LastInsertId()
-	if err != nil {
-		logError("Failed to get last insert ID", err)
-		return nil, err
-	}
-
-	site := &Site{
-		ID:   int(id),
-		Name: name,
-		URL:  url,
-	}
-
-	logSuccess(fmt.Sprintf("Created site with ID %d", id))
-	return site, nil
+	logSuccess(fmt.Sprintf("Created site with ID %d", site.ID))
+	return &site, nil
 }
 
-// UpdateSite updates an existing site in the database
-func (c *SQLClient) UpdateSite(id int, name, url string) (*Site, error) {
-	result, err := c.db.Exec("UPDATE sites SET site_url = ?, site_name = ? WHERE id = ?", url, name, id)
-	if err != nil {
+// UpdateSite updates an existing site in the database and returns it with the
+// columns the update leaves alone
+func (c *SQLClient) UpdateSite(id int, name, url, clientType, clientConfig string) (*Site, error) {
+	site, err := scanSite(c.db.QueryRow(`UPDATE sites
+		SET site_url = ?, site_name = ?, client_type = ?, client_config = ?
+		WHERE id = ?
+		RETURNING `+siteColumns,
+		url, name, clientType, clientConfig, id))
+	if err == sql.ErrNoRows {
+		return nil, sql.ErrNoRows
+	} else if err != nil {
 		logError(fmt.Sprintf("Failed to update site with ID %d", id), err)
 		return nil, err
 	}
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		logError("Failed to get rows affected", err)
-		return nil, err
-	}
+	logSuccess(fmt.Sprintf("Updated site with ID %d", id))
+	return &site, nil
+}
 
-	if rowsAffected == 0 {
+// UpdateSiteSchedule stores when the scheduler fetches a site and returns the site
+func (c *SQLClient) UpdateSiteSchedule(id int, schedule string, jitterSeconds int, quietHoursStart, quietHoursEnd *int) (*Site, error) {
+	site, err := scanSite(c.db.QueryRow(`UPDATE sites
+		SET schedule = ?, schedule_jitter_seconds = ?, quiet_hours_start = ?, quiet_hours_end = ?
+		WHERE id = ?
+		RETURNING `+siteColumns,
+		schedule, jitterSeconds, quietHoursStart, quietHoursEnd, id))
+	if err == sql.ErrNoRows {
 		return nil, sql.ErrNoRows
+	} else if err != nil {
+		logError(fmt.Sprintf("Failed to update schedule of site with ID %d", id), err)
+		return nil, err
 	}
 
-	site := &Site{
-		ID:   id,
-		Name: name,
-		URL:  url,
-	}
-
-	logSuccess(fmt.Sprintf("Updated site with ID %d", id))
-	return site, nil
+	logSuccess(fmt.Sprintf("Updated schedule of site with ID %d", id))
+	return &site, nil
 }
 
 // DeleteSite deletes a site from the database
@@ -223,13 +329,29 @@ func (c *SQLClient) DeleteSite(id int) error {
 	return nil
 }
 
-// CreatePart creates a new part in the database
-func (c *SQLClient) CreatePart(partID, description, typeName, name, imageBase64, url string, siteID int, price string, creationDate time.Time) (*Part, error) {
+// priceAmountValue returns the value stored in price_amount: NULL when the amount is unknown
+func priceAmountValue(amount int64, priceType string) interface{} {
+	if priceType == "" || priceType == "on_request" {
+		return nil
+	}
+	return amount
+}
+
+// displayAmountSQL converts the price_amount of a part into the display currency, given
+// as parameter. It is NULL when either currency has no exchange rate.
+const displayAmountSQL = `CAST(ROUND(price_amount
+		* (SELECT rate FROM exchange_rates WHERE currency = ?)
+		/ (SELECT rate FROM exchange_rates WHERE currency = parts.price_currency)) AS INTEGER)`
+
+// CreatePart creates a new part in the database. Its image is downloaded later from imageSourceURL.
+// priceAmount is in cents and ignored when priceType is empty or "on_request".
+func (c *SQLClient) CreatePart(partID, description, typeName, name, imageSourceURL, url string, siteID int, price string, priceAmount int64, priceCurrency, priceType string, creationDate time.Time) (*Part, error) {
 	formattedDate := creationDate.Format("2006-01-02 15:04:05")
+	amount := priceAmountValue(priceAmount, priceType)
 	result, err := c.db.Exec(`
-		INSERT INTO parts (part_id, description, type_name, name, image_base64, url, site_id, price, last_seen, creation_date)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, ?)
-	`, partID, description, typeName, name, imageBase64, url, siteID, price, formattedDate)
+		INSERT INTO parts (part_id, description, type_name, name, image_source_url, url, site_id, price, price_amount, price_currency, price_type, last_seen, creation_date)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, ?)
+	`, partID, description, typeName, name, imageSourceURL, url, siteID, price, amount, priceCurrency, priceType, formattedDate)
 	if err != nil {
 		logError("Failed to create part", err)
 		return nil, err
@@ -241,52 +363,235 @@ func (c *SQLClient) CreatePart(partID, description, typeName, name, imageBase64,
 		return nil, err
 	}
 
+	// Convert the price with the exchange rates in the database
+	var displayAmount sql.NullInt64
+	err = c.db.QueryRow(`UPDATE parts SET price_display_amount = `+displayAmountSQL+` WHERE id = ? RETURNING price_display_amount`,
+		c.displayCurrency, id).Scan(&displayAmount)
+	if err != nil {
+		logError(fmt.Sprintf("Failed to convert price of part %d", id), err)
+		return nil, err
+	}
+
 	part := &Part{
-		ID:          int(id),
-		PartID:      partID,
-		Description: description,
-		TypeName:    typeName,
-		Name:        name,
-		ImageBase64: imageBase64,
-		URL:         url,
-		SiteID:      siteID,
-		Price:       price,
+		ID:            int(id),
+		PartID:        partID,
+		Description:   description,
+		TypeName:      typeName,
+		Name:          name,
+		URL:           url,
+		SiteID:        siteID,
+		Price:         price,
+		PriceCurrency: priceCurrency,
+		PriceType:     priceType,
+	}
+	if amount != nil {
+		part.PriceAmount = &priceAmount
+	}
+	if displayAmount.Valid {
+		part.PriceDisplayAmount = &displayAmount.Int64
 	}
 
 	logSuccess(fmt.Sprintf("Created part with ID %d", id))
 	return part, nil
 }
 
-// GetPartByID retrieves a single part by its database ID
-func (c *SQLClient) GetPartByID(id int) (*Part, error) {
+// GetPendingImages returns the newest parts whose image has not been downloaded yet
+// and has failed fewer than maxAttempts times
+func (c *SQLClient) GetPendingImages(limit, maxAttempts int) ([]PendingImage, error) {
+	rows, err := c.db.Query(`
+		SELECT id, image_source_url FROM parts
+		WHERE image_source_url != '' AND image_attempts < ?
+		AND NOT EXISTS (SELECT 1 FROM part_images WHERE part_images.part_id = parts.id)
+		ORDER BY id DESC
+		LIMIT ?
+	`, maxAttempts, limit)
+	if err != nil {
+		logError("Failed to query pending images", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pending []PendingImage
+	for rows.Next() {
+		var image PendingImage
+		if err := rows.Scan(&image.PartID, &image.URL); err != nil {
+			logError("Failed to scan pending image", err)
+			return nil, err
+		}
+		pending = append(pending, image)
+	}
+	return pending, rows.Err()
+}
+
+// SetPartImage references a downloaded image from the image store for a part.
+// perceptualHash is nil for images that could not be decoded.
+func (c *SQLClient) SetPartImage(partID int, imageHash string, perceptualHash *uint64) error {
+	var hash interface{}
+	if perceptualHash != nil {
+		hash = int64(*perceptualHash)
+	}
+	_, err := c.db.Exec(`
+		INSERT OR REPLACE INTO part_images (part_id, position, image_hash, perceptual_hash) VALUES (?, 0, ?, ?)
+	`, partID, imageHash, hash)
+	if err != nil {
+		logError(fmt.Sprintf("Failed to set image for part %d", partID), err)
+		return err
+	}
+	return nil
+}
+
+// RecordImageFailure counts a failed image download for a part
+func (c *SQLClient) RecordImageFailure(partID int) error {
+	_, err := c.db.Exec(`UPDATE parts SET image_attempts = image_attempts + 1 WHERE id = ?`, partID)
+	if err != nil {
+		logError(fmt.Sprintf("Failed to record image failure for part %d", partID), err)
+		return err
+	}
+	return nil
+}
+
+// GetImageHashes returns the hashes of all images referenced by parts
+func (c *SQLClient) GetImageHashes() (map[string]bool, error) {
+	rows, err := c.db.Query(`SELECT DISTINCT image_hash FROM part_images`)
+	if err != nil {
+		logError("Failed to query image hashes", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	hashes := make(map[string]bool)
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			logError("Failed to scan image hash", err)
+			return nil, err
+		}
+		hashes[hash] = true
+	}
+	return hashes, rows.Err()
+}
+
+// highestPriceSQL is the highest earlier price_amount of a part in its current currency,
+// NULL when its price never changed
+const highestPriceSQL = `(SELECT MAX(old_price_amount) FROM part_price_history
+		WHERE part_price_history.part_id = parts.id AND old_price_currency = parts.price_currency)`
+
+// partColumns is the column list shared by every query that returns full parts
+const partColumns = `id, part_id, description, type_name, name, url, site_id, price, price_amount, price_currency, price_type, price_display_amount, created_at, updated_at, last_seen, creation_date,
+		image_source_url, edited_fields, edited_at, status, status_changed_at, type_overridden, cluster_id,
+		` + highestPriceSQL + ` - price_amount AS price_drop_amount,
+		(SELECT GROUP_CONCAT(profile_id) FROM part_profiles WHERE part_profiles.part_id = parts.id) AS profile_ids,
+		(SELECT GROUP_CONCAT(number) FROM part_numbers WHERE part_numbers.part_id = parts.id) AS part_numbers,
+		(SELECT GROUP_CONCAT(duplicates.id) FROM parts AS duplicates
+			WHERE duplicates.cluster_id = parts.cluster_id AND duplicates.id != parts.id) AS duplicate_ids,
+		(SELECT image_hash FROM part_images WHERE part_images.part_id = parts.id ORDER BY position LIMIT 1) AS image_hash`
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanPart scans a row selected with partColumns into a Part
+func scanPart(scanner rowScanner) (Part, error) {
 	var part Part
 	var price sql.NullString
-	var creationDate sql.NullString
-	err := c.db.QueryRow(`
-		SELECT id, part_id, description, type_name, name, image_base64, url, site_id, price, created_at, updated_at, last_seen, creation_date
-		FROM parts WHERE id = ?
-	`, id).Scan(
+	var profileIDs sql.NullString
+	var partNumbers sql.NullString
+	var clusterID sql.NullInt64
+	var duplicateIDs sql.NullString
+	var imageHash sql.NullString
+	var priceAmount sql.NullInt64
+	var priceDisplayAmount sql.NullInt64
+	var priceDropAmount sql.NullInt64
+	var editedFields string
+	err := scanner.Scan(
 		&part.ID, &part.PartID, &part.Description, &part.TypeName,
-		&part.Name, &part.ImageBase64, &part.URL, &part.SiteID, &price,
-		&part.CreatedAt, &part.UpdatedAt, &part.LastSeen, &creationDate,
+		&part.Name, &part.URL, &part.SiteID, &price,
+		&priceAmount, &part.PriceCurrency, &part.PriceType, &priceDisplayAmount,
+		&part.CreatedAt, &part.UpdatedAt, &part.LastSeen, &part.CreationDate,
+		&part.ImageSourceURL, &editedFields, &part.EditedAt, &part.Status, &part.StatusChangedAt, &part.TypeOverridden, &clusterID,
+		&priceDropAmount, &profileIDs, &partNumbers, &duplicateIDs, &imageHash,
 	)
-	if creationDate.Valid {
-		parsedTime, err := time.Parse("2006-01-02 15:04:05", creationDate.String)
-		if err == nil {
-			part.CreationDate = &parsedTime
-		}
+	if err != nil {
+		return part, err
 	}
-
 	if price.Valid {
 		part.Price = price.String
 	}
+	if priceAmount.Valid {
+		part.PriceAmount = &priceAmount.Int64
+	}
+	if priceDisplayAmount.Valid {
+		part.PriceDisplayAmount = &priceDisplayAmount.Int64
+	}
+	if priceDropAmount.Valid && priceDropAmount.Int64 > 0 {
+		part.PriceDropAmount = &priceDropAmount.Int64
+	}
+	part.ProfileIDs = parseIDList(profileIDs.String)
+	part.PartNumbers = parseFieldList(partNumbers.String)
+	if clusterID.Valid {
+		id := int(clusterID.Int64)
+		part.ClusterID = &id
+	}
+	part.DuplicateIDs = parseIDList(duplicateIDs.String)
+	part.EditedFields = parseFieldList(editedFields)
+	// Lists show the small thumbnail, single parts replace it with the large one
+	part.ImageHash = imageHash.String
+	part.ImageURL = ImageURL(part.ImageHash, images.SizeSmall)
+	return part, nil
+}
+
+// scanParts scans all rows selected with partColumns
+func scanParts(rows *sql.Rows) ([]Part, error) {
+	var parts []Part
+	for rows.Next() {
+		part, err := scanPart(rows)
+		if err != nil {
+			logError("Failed to scan part data", err)
+			return nil, err
+		}
+		parts = append(parts, part)
+	}
+
+	if err := rows.Err(); err != nil {
+		logError("Error iterating parts", err)
+		return nil, err
+	}
+	return parts, nil
+}
+
+// parseIDList parses a comma separated list of IDs as produced by GROUP_CONCAT
+func parseIDList(list string) []int {
+	ids := make([]int, 0)
+	for _, field := range strings.Split(list, ",") {
+		if id, err := strconv.Atoi(strings.TrimSpace(field)); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
 
+// parseFieldList parses a comma separated list of names, like the field names stored in edited_fields
+func parseFieldList(list string) []string {
+	fields := make([]string, 0)
+	for _, field := range strings.Split(list, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// GetPartByID retrieves a single part by its database ID
+func (c *SQLClient) GetPartByID(id int) (*Part, error) {
+	part, err := scanPart(c.db.QueryRow("SELECT "+partColumns+" FROM parts WHERE id = ?", id))
 	if err == sql.ErrNoRows {
 		return nil, sql.ErrNoRows
 	} else if err != nil {
 		logError(fmt.Sprintf("Failed to query part with ID %d", id), err)
 		return nil, err
 	}
+	part.ImageURL = ImageURL(part.ImageHash, images.SizeLarge)
 
 	logSuccess(fmt.Sprintf("Retrieved part with ID %d", id))
 	return &part, nil
@@ -295,7 +600,7 @@ func (c *SQLClient) GetPartByID(id int) (*Part, error) {
 // GetPartsBySiteID retrieves all parts for a specific site
 func (c *SQLClient) GetPartsBySiteID(siteID int, limit, offset int) ([]Part, error) {
 	query := `
-		SELECT id, part_id, description, type_name, name, image_base64, url, site_id, price, created_at, updated_at, last_seen, creation_date
+		SELECT ` + partColumns + `
 		FROM parts
 		WHERE site_id = ?
 		ORDER BY created_at DESC
@@ -309,34 +614,8 @@ func (c *SQLClient) GetPartsBySiteID(siteID int, limit, offset int) ([]Part, err
 	}
 	defer rows.Close()
 
-	var parts []Part
-	for rows.Next() {
-		var part Part
-		var price sql.NullString
-		var creationDate sql.NullString
-		err := rows.Scan(
-			&part.ID, &part.PartID, &part.Description, &part.TypeName,
-			&part.Name, &part.ImageBase64, &part.URL, &part.SiteID, &price,
-			&part.CreatedAt, &part.UpdatedAt, &part.LastSeen, &creationDate,
-		)
-		if creationDate.Valid {
-			parsedTime, err := time.Parse("2006-01-02 15:04:05", creationDate.String)
-			if err == nil {
-				part.CreationDate = &parsedTime
-			}
-		}
-		if err != nil {
-			logError("Failed to scan part data", err)
-			return nil, err
-		}
-		if price.Valid {
-			part.Price = price.String
-		}
-		parts = append(parts, part)
-	}
-
-	if err = rows.Err(); err != nil {
-		logError("Error iterating parts", err)
+	parts, err := scanParts(rows)
+	if err != nil {
 		return nil, err
 	}
 
@@ -345,39 +624,24 @@ func (c *SQLClient) GetPartsBySiteID(siteID int, limit, offset int) ([]Part, err
 }
 
 // GetFilteredParts retrieves filtered parts from the database
-func (c *SQLClient) GetFilteredParts(limit, offset int, typeFilter string, siteIDs []int, newerThan time.Time, search string, sortBy string, sortDesc bool) ([]Part, error) {
+func (c *SQLClient) GetFilteredParts(limit, offset int, filter PartsFilter, sortBy string, sortDesc bool) ([]Part, error) {
 	queryBuilder := strings.Builder{}
-	params := make([]interface{}, 0)
 
 	queryBuilder.WriteString(`
-		SELECT id, part_id, description, type_name, name, image_base64, url, site_id, price, created_at, updated_at, last_seen, creation_date
-		FROM parts
-		WHERE 1=1`)
-
-	if typeFilter != "" {
-		queryBuilder.WriteString(" AND type_name = ?")
-		params = append(params, typeFilter)
-	}
-
-	if len(siteIDs) > 0 {
-		placeholders := make([]string, len(siteIDs))
-		for i := range siteIDs {
-			placeholders[i] = "?"
-			params = append(params, siteIDs[i])
-		}
-		queryBuilder.WriteString(" AND site_id IN (" + strings.Join(placeholders, ",") + ")")
-	}
+		SELECT ` + partColumns + `
+		FROM parts`)
 
-	if !newerThan.IsZero() {
-		queryBuilder.WriteString(" AND creation_date > ?")
-		params = append(params, newerThan)
+	// Ranking by relevance joins the bm25 rank of every matching part
+	params := make([]interface{}, 0)
+	searchQuery := ftsQuery(filter.Search)
+	if sortBy == "relevance" && searchQuery != "" {
+		queryBuilder.WriteString(" JOIN (" + ftsRankSQL + ") AS search ON search.match_id = parts.id")
+		params = append(params, searchQuery)
 	}
 
-	if search != "" {
-		queryBuilder.WriteString(" AND (name LIKE ? OR description LIKE ? OR type_name LIKE ?)")
-		searchPattern := "%" + search + "%"
-		params = append(params, searchPattern, searchPattern, searchPattern)
-	}
+	where, whereParams := buildPartsFilter(filter)
+	queryBuilder.WriteString(" WHERE 1=1" + where)
+	params = append(params, whereParams...)
 
 	// Handle sorting
 	switch sortBy {
@@ -393,8 +657,25 @@ func (c *SQLClient) GetFilteredParts(limit, offset int, typeFilter string, siteI
 		} else {
 			queryBuilder.WriteString(" ORDER BY name ASC")
 		}
+	case "price_asc", "price_desc":
+		// Parts without a known amount go last in both directions
+		if sortDesc {
+			queryBuilder.WriteString(" ORDER BY price_display_amount IS NULL, price_display_amount DESC")
+		} else {
+			queryBuilder.WriteString(" ORDER BY price_display_amount IS NULL, price_display_amount ASC")
+		}
+	case "price_drop":
+		// Biggest drop relative to the highest earlier price first, parts that did not drop last
+		queryBuilder.WriteString(" ORDER BY " + highestPriceSQL + " > price_amount DESC, price_amount * 1.0 / " + highestPriceSQL + " ASC")
 	case "recent_seen":
 		queryBuilder.WriteString(" ORDER BY last_seen DESC")
+	case "relevance":
+		// bm25 ranks better matches lower; without a search term there is nothing to rank
+		if searchQuery != "" {
+			queryBuilder.WriteString(" ORDER BY search.match_rank ASC, created_at DESC")
+		} else {
+			queryBuilder.WriteString(" ORDER BY created_at DESC")
+		}
 	default:
 		// Default to newest first by created_at
 		queryBuilder.WriteString(" ORDER BY created_at DESC")
@@ -405,83 +686,33 @@ func (c *SQLClient) GetFilteredParts(limit, offset int, typeFilter string, siteI
 
 	rows, err := c.db.Query(queryBuilder.String(), params...)
 	if err != nil {
-		// logError(fmt.Sprintf("Failed to query parts for site ID %d", siteID), err)
+		logError("Failed to query filtered parts", err)
 		return nil, err
 	}
 	defer rows.Close()
 
-	var parts []Part
-	for rows.Next() {
-		var part Part
-		var price sql.NullString
-		err := rows.Scan(
-			&part.ID, &part.PartID, &part.Description, &part.TypeName,
-			&part.Name, &part.ImageBase64, &part.URL, &part.SiteID, &price,
-			&part.CreatedAt, &part.UpdatedAt, &part.LastSeen, &part.CreationDate,
-		)
-		if err != nil {
-			logError("Failed to scan part data", err)
-			return nil, err
-		}
-		if price.Valid {
-			part.Price = price.String
-		}
-		parts = append(parts, part)
-	}
-
-	if err = rows.Err(); err != nil {
-		logError("Error iterating parts", err)
-		return nil, err
-	}
-
-	// logSuccess(fmt.Sprintf("Retrieved %d parts for site ID %d", len(parts), siteID))
-	return parts, nil
+	return scanParts(rows)
 }
 
-// / GetAllParts retrieves all parts
+// GetAllParts retrieves all parts that are not gone from their site
 func (c *SQLClient) GetAllParts(limit, offset int) ([]Part, error) {
 	query := `
-		SELECT id, part_id, description, type_name, name, image_base64, url, site_id, price, created_at, updated_at, last_seen, creation_date
+		SELECT ` + partColumns + `
 		FROM parts
+		WHERE status != ?
 		ORDER BY created_at DESC
 		LIMIT ? OFFSET ?
 	`
 
-	rows, err := c.db.Query(query, limit, offset)
+	rows, err := c.db.Query(query, PartStatusGone, limit, offset)
 	if err != nil {
 		logError("Failed to query parts", err)
 		return nil, err
 	}
 	defer rows.Close()
 
-	var parts []Part
-	for rows.Next() {
-		var part Part
-		var price sql.NullString
-		var creationDate sql.NullString
-		err := rows.Scan(
-			&part.ID, &part.PartID, &part.Description, &part.TypeName,
-			&part.Name, &part.ImageBase64, &part.URL, &part.SiteID, &price,
-			&part.CreatedAt, &part.UpdatedAt, &part.LastSeen, &creationDate,
-		)
-		if creationDate.Valid {
-			parsedTime, err := time.Parse("2006-01-02 15:04:05", creationDate.String)
-			if err == nil {
-				part.CreationDate = &parsedTime
-			}
-		}
-		if err != nil {
-			logError("Failed to scan part data", err)
-			return nil, err
-		}
-		if price.Valid {
-			part.Price = price.String
-		}
-		parts = append(parts, part)
-	}
-
-	if err = rows.Err(); err != nil {
-		logError("Error iterating parts", err)
+	parts, err := scanParts(rows)
+	if err != nil {
 		return nil, err
 	}
 
@@ -489,11 +720,11 @@ func (c *SQLClient) GetAllParts(limit, offset int) ([]Part, error) {
 	return parts, nil
 }
 
-// GetExistingPartIDs checks which part IDs already exist in the database for a given site
-// Returns a map where keys are part IDs that exist
-func (c *SQLClient) GetExistingPartIDs(partIDs []string, siteID int) (map[string]bool, error) {
+// GetExistingParts retrieves the stored parts of a site with the given part IDs,
+// keyed by part ID
+func (c *SQLClient) GetExistingParts(partIDs []string, siteID int) (map[string]Part, error) {
 	if len(partIDs) == 0 {
-		return make(map[string]bool), nil
+		return make(map[string]Part), nil
 	}
 
 	// Build a query with placeholders for the IN clause
@@ -507,54 +738,54 @@ func (c *SQLClient) GetExistingPartIDs(partIDs []string, siteID int) (map[string
 	}
 
 	query := fmt.Sprintf(`
-		SELECT part_id FROM parts
+		SELECT `+partColumns+` FROM parts
 		WHERE site_id = ? AND part_id IN (%s)
 	`, strings.Join(placeholders, ","))
 
 	rows, err := c.db.Query(query, args...)
 	if err != nil {
-		logError("Failed to query existing part IDs", err)
+		logError("Failed to query existing parts", err)
 		return nil, err
 	}
 	defer rows.Close()
 
-	existingParts := make(map[string]bool)
-	for rows.Next() {
-		var partID string
-		if err := rows.Scan(&partID); err != nil {
-			logError("Failed to scan part ID", err)
-			return nil, err
-		}
-		existingParts[partID] = true
+	parts, err := scanParts(rows)
+	if err != nil {
+		return nil, err
 	}
 
-	if err = rows.Err(); err != nil {
-		logError("Error iterating existing part IDs", err)
-		return nil, err
+	existingParts := make(map[string]Part, len(parts))
+	for _, part := range parts {
+		existingParts[part.PartID] = part
 	}
 
 	log.Printf("Found %d existing parts out of %d checked for site ID %d", len(existingParts), len(partIDs), siteID)
 	return existingParts, nil
 }
 
-// UpdateLastSeen updates the last_seen timestamp for multiple parts
+// UpdateLastSeen updates the last_seen timestamp for multiple parts, making missing
+// and gone parts active again
 func (c *SQLClient) UpdateLastSeen(partIDs []string, siteID int) error {
 	if len(partIDs) == 0 {
 		return nil
 	}
 
 	placeholders := make([]string, len(partIDs))
-	args := make([]interface{}, len(partIDs)+1)
-	args[0] = siteID
+	args := make([]interface{}, len(partIDs)+3)
+	args[0] = PartStatusActive
+	args[1] = PartStatusActive
+	args[2] = siteID
 
 	for i, partID := range partIDs {
 		placeholders[i] = "?"
-		args[i+1] = partID
+		args[i+3] = partID
 	}
 
 	query := fmt.Sprintf(`
 		UPDATE parts
-		SET last_seen = CURRENT_TIMESTAMP
+		SET last_seen = CURRENT_TIMESTAMP,
+			status_changed_at = CASE WHEN status != ? THEN CURRENT_TIMESTAMP ELSE status_changed_at END,
+			status = ?
 		WHERE site_id = ? AND part_id IN (%s)
 	`, strings.Join(placeholders, ","))
 
@@ -574,53 +805,293 @@ func (c *SQLClient) UpdateLastSeen(partIDs []string, siteID int) error {
 	return nil
 }
 
-// DeleteStaleParts deletes parts that haven't been seen since a specific time
-func (c *SQLClient) DeleteStaleParts(siteID int, olderThan time.Time) (int64, error) {
-	result, err := c.db.Exec(`
-		DELETE FROM parts
-		WHERE site_id = ? AND last_seen < ?
-	`, siteID, olderThan)
+// UpdatePartStatuses marks the active parts of a site last seen before missingBefore
+// as missing, and the parts last seen before goneBefore as gone. It returns the
+// number of parts that went missing and the IDs of the parts that went gone.
+func (c *SQLClient) UpdatePartStatuses(siteID int, missingBefore, goneBefore time.Time) (int64, []int, error) {
+	tx, err := c.db.Begin()
 	if err != nil {
-		logError(fmt.Sprintf("Failed to delete stale parts for site ID %d", siteID), err)
-		return 0, err
+		logError("Failed to begin transaction", err)
+		return 0, nil, err
 	}
+	defer tx.Rollback()
 
-	rowsAffected, err := result.RowsAffected()
+	// Gone first, so parts that skip missing do not count as both
+	goneIDs, err := c.setStatus(tx, siteID, PartStatusGone, goneBefore, PartStatusActive, PartStatusMissing)
 	if err != nil {
-		logError("Failed to get rows affected for stale parts deletion", err)
-		return 0, err
+		return 0, nil, err
+	}
+	missingIDs, err := c.setStatus(tx, siteID, PartStatusMissing, missingBefore, PartStatusActive)
+	if err != nil {
+		return 0, nil, err
 	}
 
-	log.Printf("Deleted %d stale parts for site ID %d (older than %s)", rowsAffected, siteID, olderThan.Format("2006-01-02 15:04:05"))
-	return rowsAffected, nil
+	if err := tx.Commit(); err != nil {
+		logError(fmt.Sprintf("Failed to commit part statuses for site ID %d", siteID), err)
+		return 0, nil, err
+	}
+
+	log.Printf("Marked %d parts missing and %d parts gone for site ID %d", len(missingIDs), len(goneIDs), siteID)
+	return int64(len(missingIDs)), goneIDs, nil
 }
 
-// UpdatePart updates an existing part in the database
-func (c *SQLClient) UpdatePart(id int, partID, description, typeName, name, imageBase64, url string, siteID int, price string) (*Part, error) {
-	result, err := c.db.Exec(`
+// setStatus changes the status of the parts of a site with one of the from statuses
+// that were last seen before a time, returning the IDs of the changed parts
+func (c *SQLClient) setStatus(tx *sql.Tx, siteID int, status string, seenBefore time.Time, from ...string) ([]int, error) {
+	placeholders := make([]string, len(from))
+	args := []interface{}{status, siteID, seenBefore.UTC().Format("2006-01-02 15:04:05")}
+	for i, fromStatus := range from {
+		placeholders[i] = "?"
+		args = append(args, fromStatus)
+	}
+
+	rows, err := tx.Query(fmt.Sprintf(`
 		UPDATE parts
-		SET part_id = ?, description = ?, type_name = ?, name = ?, image_base64 = ?, url = ?, site_id = ?, price = ?, updated_at = CURRENT_TIMESTAMP, last_seen = CURRENT_TIMESTAMP
-		WHERE id = ?
-	`, partID, description, typeName, name, imageBase64, url, siteID, price, id)
+		SET status = ?, status_changed_at = CURRENT_TIMESTAMP
+		WHERE site_id = ? AND last_seen < ? AND status IN (%s)
+		RETURNING id
+	`, strings.Join(placeholders, ",")), args...)
 	if err != nil {
-		logError(fmt.Sprintf("Failed to update part with ID %d", id), err)
+		logError(fmt.Sprintf("Failed to mark parts %s for site ID %d", status, siteID), err)
 		return nil, err
 	}
+	defer rows.Close()
+
+	ids := make([]int, 0)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			logError("Failed to scan part ID", err)
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		logError("Error iterating changed parts", err)
+		return nil, err
+	}
+	return ids, nil
+}
+
+// PurgeGoneParts deletes the parts of a site that have been gone since before a time
+func (c *SQLClient) PurgeGoneParts(siteID int, goneBefore time.Time) (int64, error) {
+	result, err := c.db.Exec(`
+		DELETE FROM parts
+		WHERE site_id = ? AND status = ? AND status_changed_at < ?
+	`, siteID, PartStatusGone, goneBefore.UTC().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		logError(fmt.Sprintf("Failed to purge gone parts for site ID %d", siteID), err)
+		return 0, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		logError("Failed to get rows affected for gone parts purge", err)
+		return 0, err
+	}
+
+	log.Printf("Purged %d gone parts for site ID %d (gone before %s)", rowsAffected, siteID, goneBefore.Format("2006-01-02 15:04:05"))
+	return rowsAffected, nil
+}
+
+// UpdatePart writes the fetched fields of an existing part and records which fields
+// changed as its last edit. A changed price is added to the price history and a
+// changed image is downloaded again from imageSourceURL.
+// priceAmount is in cents and ignored when priceType is empty or "on_request".
+func (c *SQLClient) UpdatePart(id int, description, typeName, name, imageSourceURL, url string, price string, priceAmount int64, priceCurrency, priceType string, changedFields []string) error {
+	amount := priceAmountValue(priceAmount, priceType)
+
+	tx, err := c.db.Begin()
+	if err != nil {
+		logError("Failed to begin transaction", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	if slices.Contains(changedFields, FieldPrice) {
+		_, err = tx.Exec(`
+			INSERT INTO part_price_history (part_id, old_price, old_price_amount, old_price_currency, old_price_type, new_price, new_price_amount, new_price_currency, new_price_type)
+			SELECT id, COALESCE(price, ''), price_amount, price_currency, price_type, ?, ?, ?, ? FROM parts WHERE id = ?
+		`, price, amount, priceCurrency, priceType, id)
+		if err != nil {
+			logError(fmt.Sprintf("Failed to record price change of part %d", id), err)
+			return err
+		}
+	}
+
+	result, err := tx.Exec(`
+		UPDATE parts
+		SET description = ?, type_name = ?, name = ?, image_source_url = ?, url = ?,
+			price = ?, price_amount = ?, price_currency = ?, price_type = ?,
+			edited_fields = ?, edited_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP, last_seen = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`, description, typeName, name, imageSourceURL, url, price, amount, priceCurrency, priceType, strings.Join(changedFields, ","), id)
+	if err != nil {
+		logError(fmt.Sprintf("Failed to update part with ID %d", id), err)
+		return err
+	}
 
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
 		logError("Failed to get rows affected", err)
-		return nil, err
+		return err
 	}
 
 	if rowsAffected == 0 {
-		return nil, sql.ErrNoRows
+		return sql.ErrNoRows
+	}
+
+	// Convert the new price, which the update above cannot do as it sees the old one
+	if _, err := tx.Exec(`UPDATE parts SET price_display_amount = `+displayAmountSQL+` WHERE id = ?`, c.displayCurrency, id); err != nil {
+		logError(fmt.Sprintf("Failed to convert price of part %d", id), err)
+		return err
+	}
+
+	// The old image is pruned once no part references it anymore
+	if slices.Contains(changedFields, FieldImage) {
+		if _, err := tx.Exec(`DELETE FROM part_images WHERE part_id = ?`, id); err != nil {
+			logError(fmt.Sprintf("Failed to remove old image of part %d", id), err)
+			return err
+		}
+		if _, err := tx.Exec(`UPDATE parts SET image_attempts = 0 WHERE id = ?`, id); err != nil {
+			logError(fmt.Sprintf("Failed to reset image attempts of part %d", id), err)
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		logError(fmt.Sprintf("Failed to commit update of part %d", id), err)
+		return err
+	}
+
+	logSuccess(fmt.Sprintf("Updated %s of part with ID %d", strings.Join(changedFields, ", "), id))
+	return nil
+}
+
+// SetPartType sets the type of a part by hand, so the classifier leaves it alone.
+// An empty typeName hands the part back to the classifier.
+func (c *SQLClient) SetPartType(id int, typeName string) (*Part, error) {
+	var err error
+	if typeName != "" {
+		_, err = c.db.Exec(`UPDATE parts SET type_name = ?, type_overridden = 1 WHERE id = ?`, typeName, id)
+	} else {
+		part, getErr := c.GetPartByID(id)
+		if getErr != nil {
+			return nil, getErr
+		}
+		_, err = c.db.Exec(`UPDATE parts SET type_name = ?, type_overridden = 0 WHERE id = ?`,
+			categories.Classify(part.Name, part.Description), id)
+	}
+	if err != nil {
+		logError(fmt.Sprintf("Failed to set type of part %d", id), err)
+		return nil, err
 	}
 
-	// Fetch and return the updated part
+	logSuccess(fmt.Sprintf("Set type of part with ID %d", id))
 	return c.GetPartByID(id)
 }
 
+// ReclassifyParts classifies every part whose type was not set by hand again and
+// returns the number of parts whose type changed
+func (c *SQLClient) ReclassifyParts(ctx context.Context) (int, error) {
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		logError("Failed to begin transaction", err)
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	changed, err := reclassifyParts(ctx, tx)
+	if err != nil {
+		logError("Failed to reclassify parts", err)
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		logError("Failed to commit reclassified parts", err)
+		return 0, err
+	}
+
+	logSuccess(fmt.Sprintf("Reclassified %d parts", changed))
+	return changed, nil
+}
+
+// reclassifyParts sets the type of every part not overridden by hand to the type
+// its name and description classify as, returning the number of changed parts
+func reclassifyParts(ctx context.Context, tx *sql.Tx) (int, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT id, name, description, type_name FROM parts WHERE type_overridden = 0`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query parts: %w", err)
+	}
+
+	types := make(map[int]string)
+	for rows.Next() {
+		var id int
+		var name, description, typeName string
+		if err := rows.Scan(&id, &name, &description, &typeName); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan part: %w", err)
+		}
+		if classified := categories.Classify(name, description); classified != typeName {
+			types[id] = classified
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read parts: %w", err)
+	}
+
+	for id, typeName := range types {
+		if _, err := tx.ExecContext(ctx, `UPDATE parts SET type_name = ? WHERE id = ?`, typeName, id); err != nil {
+			return 0, fmt.Errorf("failed to update type of part %d: %w", id, err)
+		}
+	}
+	return len(types), nil
+}
+
+// GetPriceHistory retrieves the price changes of a part, oldest first
+func (c *SQLClient) GetPriceHistory(partID int) ([]PriceChange, error) {
+	rows, err := c.db.Query(`
+		SELECT id, part_id, old_price, old_price_amount, old_price_currency, old_price_type,
+			new_price, new_price_amount, new_price_currency, new_price_type, changed_at
+		FROM part_price_history
+		WHERE part_id = ?
+		ORDER BY changed_at, id
+	`, partID)
+	if err != nil {
+		logError(fmt.Sprintf("Failed to query price history of part %d", partID), err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := make([]PriceChange, 0)
+	for rows.Next() {
+		var change PriceChange
+		var oldAmount, newAmount sql.NullInt64
+		err := rows.Scan(
+			&change.ID, &change.PartID, &change.OldPrice, &oldAmount, &change.OldPriceCurrency, &change.OldPriceType,
+			&change.NewPrice, &newAmount, &change.NewPriceCurrency, &change.NewPriceType, &change.ChangedAt,
+		)
+		if err != nil {
+			logError("Failed to scan price change", err)
+			return nil, err
+		}
+		if oldAmount.Valid {
+			change.OldPriceAmount = &oldAmount.Int64
+		}
+		if newAmount.Valid {
+			change.NewPriceAmount = &newAmount.Int64
+		}
+		changes = append(changes, change)
+	}
+
+	if err = rows.Err(); err != nil {
+		logError("Error iterating price history", err)
+		return nil, err
+	}
+	return changes, nil
+}
+
 // DeletePart deletes a part from the database
 func (c *SQLClient) DeletePart(id int) error {
 	result, err := c.db.Exec("DELETE FROM parts WHERE id = ?", id)
@@ -660,3 +1131,905 @@ func (c *SQLClient) DeletePartsBySiteID(siteID int) error {
 	logSuccess(fmt.Sprintf("Deleted %d parts for site ID %d", rowsAffected, siteID))
 	return nil
 }
+
+// TagPartsWithProfile records that the given profile found the listed parts of a site
+func (c *SQLClient) TagPartsWithProfile(partIDs []string, siteID int, profileID int) error {
+	if len(partIDs) == 0 {
+		return nil
+	}
+
+	placeholders := make([]string, len(partIDs))
+	args := make([]interface{}, len(partIDs)+2)
+	args[0] = profileID
+	args[1] = siteID
+
+	for i, partID := range partIDs {
+		placeholders[i] = "?"
+		args[i+2] = partID
+	}
+
+	query := fmt.Sprintf(`
+		INSERT OR IGNORE INTO part_profiles (part_id, profile_id)
+		SELECT id, ? FROM parts
+		WHERE site_id = ? AND part_id IN (%s)
+	`, strings.Join(placeholders, ","))
+
+	if _, err := c.db.Exec(query, args...); err != nil {
+		logError(fmt.Sprintf("Failed to tag parts with profile %d", profileID), err)
+		return err
+	}
+	return nil
+}
+
+// SetPartNumbers replaces the OEM part numbers of a part
+func (c *SQLClient) SetPartNumbers(partID int, numbers []string) error {
+	tx, err := c.db.Begin()
+	if err != nil {
+		logError("Failed to begin transaction", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := replacePartNumbers(context.Background(), tx, partID, numbers); err != nil {
+		logError("Failed to set part numbers", err)
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		logError(fmt.Sprintf("Failed to commit part numbers of part %d", partID), err)
+		return err
+	}
+	return nil
+}
+
+// replacePartNumbers replaces the OEM part numbers of a part within a transaction
+func replacePartNumbers(ctx context.Context, tx *sql.Tx, partID int, numbers []string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM part_numbers WHERE part_id = ?`, partID); err != nil {
+		return fmt.Errorf("failed to remove part numbers of part %d: %w", partID, err)
+	}
+	for _, number := range numbers {
+		if _, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO part_numbers (part_id, number) VALUES (?, ?)`, partID, number); err != nil {
+			return fmt.Errorf("failed to add part number %s to part %d: %w", number, partID, err)
+		}
+	}
+	return nil
+}
+
+// GetUnhashedImages returns the hashes of the stored images that have no perceptual
+// hash yet: images stored before duplicates were detected, and those that could not
+// be decoded
+func (c *SQLClient) GetUnhashedImages() ([]string, error) {
+	rows, err := c.db.Query(`SELECT DISTINCT image_hash FROM part_images WHERE perceptual_hash IS NULL`)
+	if err != nil {
+		logError("Failed to query unhashed images", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	imageHashes := make([]string, 0)
+	for rows.Next() {
+		var imageHash string
+		if err := rows.Scan(&imageHash); err != nil {
+			logError("Failed to scan unhashed image", err)
+			return nil, err
+		}
+		imageHashes = append(imageHashes, imageHash)
+	}
+	return imageHashes, rows.Err()
+}
+
+// SetPerceptualHash sets the perceptual hash of an image for every part referencing it
+func (c *SQLClient) SetPerceptualHash(imageHash string, perceptualHash uint64) error {
+	_, err := c.db.Exec(`UPDATE part_images SET perceptual_hash = ? WHERE image_hash = ?`, int64(perceptualHash), imageHash)
+	if err != nil {
+		logError(fmt.Sprintf("Failed to set perceptual hash of image %s", imageHash), err)
+		return err
+	}
+	return nil
+}
+
+// GetDuplicateListings returns what duplicate detection compares of every part that
+// is not gone from its site
+func (c *SQLClient) GetDuplicateListings() ([]duplicates.Listing, error) {
+	rows, err := c.db.Query(`
+		SELECT id, site_id, name, price_display_amount,
+			(SELECT perceptual_hash FROM part_images WHERE part_images.part_id = parts.id ORDER BY position LIMIT 1)
+		FROM parts
+		WHERE status != ?
+	`, PartStatusGone)
+	if err != nil {
+		logError("Failed to query parts for duplicate detection", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	listings := make([]duplicates.Listing, 0)
+	for rows.Next() {
+		var listing duplicates.Listing
+		var price, imageHash sql.NullInt64
+		if err := rows.Scan(&listing.ID, &listing.SiteID, &listing.Title, &price, &imageHash); err != nil {
+			logError("Failed to scan part for duplicate detection", err)
+			return nil, err
+		}
+		if price.Valid {
+			listing.Price = &price.Int64
+		}
+		if imageHash.Valid {
+			hash := uint64(imageHash.Int64)
+			listing.ImageHash = &hash
+		}
+		listings = append(listings, listing)
+	}
+
+	if err := rows.Err(); err != nil {
+		logError("Error iterating parts for duplicate detection", err)
+		return nil, err
+	}
+	return listings, nil
+}
+
+// SetClusters replaces the duplicate clusters of all parts with clusters, which maps
+// the ID of every part with duplicates to its cluster ID. It returns the number of
+// parts whose cluster changed.
+func (c *SQLClient) SetClusters(clusters map[int]int) (int, error) {
+	tx, err := c.db.Begin()
+	if err != nil {
+		logError("Failed to begin transaction", err)
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`SELECT id, cluster_id FROM parts WHERE cluster_id IS NOT NULL`)
+	if err != nil {
+		logError("Failed to query part clusters", err)
+		return 0, err
+	}
+	current := make(map[int]int)
+	for rows.Next() {
+		var id, clusterID int
+		if err := rows.Scan(&id, &clusterID); err != nil {
+			rows.Close()
+			logError("Failed to scan part cluster", err)
+			return 0, err
+		}
+		current[id] = clusterID
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		logError("Error iterating part clusters", err)
+		return 0, err
+	}
+
+	changed := 0
+	for id := range current {
+		if _, clustered := clusters[id]; clustered {
+			continue
+		}
+		if _, err := tx.Exec(`UPDATE parts SET cluster_id = NULL WHERE id = ?`, id); err != nil {
+			logError(fmt.Sprintf("Failed to remove part %d from its cluster", id), err)
+			return 0, err
+		}
+		changed++
+	}
+	for id, clusterID := range clusters {
+		if clusterID == current[id] {
+			continue
+		}
+		if _, err := tx.Exec(`UPDATE parts SET cluster_id = ? WHERE id = ?`, clusterID, id); err != nil {
+			logError(fmt.Sprintf("Failed to set cluster of part %d", id), err)
+			return 0, err
+		}
+		changed++
+	}
+
+	if err := tx.Commit(); err != nil {
+		logError("Failed to commit part clusters", err)
+		return 0, err
+	}
+	return changed, nil
+}
+
+// profileColumns is the column list shared by every query that returns search profiles
+const profileColumns = `id, name, vehicle_type, make, base_model, model, year_from, year_to, result_limit, enabled, created_at, updated_at`
+
+// scanProfile scans a row selected with profileColumns into a SearchProfile
+func scanProfile(scanner rowScanner) (SearchProfile, error) {
+	var profile SearchProfile
+	err := scanner.Scan(
+		&profile.ID, &profile.Name, &profile.VehicleType, &profile.Make, &profile.BaseModel,
+		&profile.Model, &profile.YearFrom, &profile.YearTo, &profile.Limit, &profile.Enabled,
+		&profile.CreatedAt, &profile.UpdatedAt,
+	)
+	return profile, err
+}
+
+// GetAllProfiles retrieves all search profiles, optionally only the enabled ones
+func (c *SQLClient) GetAllProfiles(enabledOnly bool) ([]SearchProfile, error) {
+	query := "SELECT " + profileColumns + " FROM search_profiles"
+	if enabledOnly {
+		query += " WHERE enabled = 1"
+	}
+	query += " ORDER BY id"
+
+	rows, err := c.db.Query(query)
+	if err != nil {
+		logError("Failed to query search profiles", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	profiles := make([]SearchProfile, 0)
+	for rows.Next() {
+		profile, err := scanProfile(rows)
+		if err != nil {
+			logError("Failed to scan search profile data", err)
+			return nil, err
+		}
+		profiles = append(profiles, profile)
+	}
+
+	if err = rows.Err(); err != nil {
+		logError("Error iterating search profiles", err)
+		return nil, err
+	}
+
+	return profiles, nil
+}
+
+// GetProfileByID retrieves a single search profile by its ID
+func (c *SQLClient) GetProfileByID(id int) (*SearchProfile, error) {
+	profile, err := scanProfile(c.db.QueryRow("SELECT "+profileColumns+" FROM search_profiles WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return nil, sql.ErrNoRows
+	} else if err != nil {
+		logError(fmt.Sprintf("Failed to query search profile with ID %d", id), err)
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// CreateProfile creates a new search profile in the database
+func (c *SQLClient) CreateProfile(profile SearchProfile) (*SearchProfile, error) {
+	result, err := c.db.Exec(`
+		INSERT INTO search_profiles (name, vehicle_type, make, base_model, model, year_from, year_to, result_limit, enabled)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, profile.Name, profile.VehicleType, profile.Make, profile.BaseModel, profile.Model,
+		profile.YearFrom, profile.YearTo, profile.Limit, profile.Enabled)
+	if err != nil {
+		logError("Failed to create search profile", err)
+		return nil, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		logError("Failed to get last insert ID for search profile", err)
+		return nil, err
+	}
+
+	logSuccess(fmt.Sprintf("Created search profile with ID %d", id))
+	return c.GetProfileByID(int(id))
+}
+
+// UpdateProfile updates an existing search profile in the database
+func (c *SQLClient) UpdateProfile(id int, profile SearchProfile) (*SearchProfile, error) {
+	result, err := c.db.Exec(`
+		UPDATE search_profiles
+		SET name = ?, vehicle_type = ?, make = ?, base_model = ?, model = ?, year_from = ?, year_to = ?, result_limit = ?, enabled = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`, profile.Name, profile.VehicleType, profile.Make, profile.BaseModel, profile.Model,
+		profile.YearFrom, profile.YearTo, profile.Limit, profile.Enabled, id)
+	if err != nil {
+		logError(fmt.Sprintf("Failed to update search profile with ID %d", id), err)
+		return nil, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		logError("Failed to get rows affected", err)
+		return nil, err
+	}
+
+	if rowsAffected == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	logSuccess(fmt.Sprintf("Updated search profile with ID %d", id))
+	return c.GetProfileByID(id)
+}
+
+// DeleteProfile deletes a search profile and its part tags from the database
+func (c *SQLClient) DeleteProfile(id int) error {
+	result, err := c.db.Exec("DELETE FROM search_profiles WHERE id = ?", id)
+	if err != nil {
+		logError(fmt.Sprintf("Failed to delete search profile with ID %d", id), err)
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		logError("Failed to get rows affected", err)
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	logSuccess(fmt.Sprintf("Deleted search profile with ID %d", id))
+	return nil
+}
+
+// savedSearchColumns is the column list shared by every query that returns saved searches
+const savedSearchColumns = `id, name, query, enabled, created_at, updated_at`
+
+// scanSavedSearch scans a row selected with savedSearchColumns into a SavedSearch
+func scanSavedSearch(scanner rowScanner) (SavedSearch, error) {
+	var search SavedSearch
+	err := scanner.Scan(&search.ID, &search.Name, &search.Query, &search.Enabled, &search.CreatedAt, &search.UpdatedAt)
+	return search, err
+}
+
+// GetAllSavedSearches retrieves all saved searches, optionally only the enabled ones
+func (c *SQLClient) GetAllSavedSearches(enabledOnly bool) ([]SavedSearch, error) {
+	query := "SELECT " + savedSearchColumns + " FROM saved_searches"
+	if enabledOnly {
+		query += " WHERE enabled = 1"
+	}
+	query += " ORDER BY id"
+
+	rows, err := c.db.Query(query)
+	if err != nil {
+		logError("Failed to query saved searches", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	searches := make([]SavedSearch, 0)
+	for rows.Next() {
+		search, err := scanSavedSearch(rows)
+		if err != nil {
+			logError("Failed to scan saved search data", err)
+			return nil, err
+		}
+		searches = append(searches, search)
+	}
+
+	if err = rows.Err(); err != nil {
+		logError("Error iterating saved searches", err)
+		return nil, err
+	}
+
+	return searches, nil
+}
+
+// GetSavedSearchByID retrieves a single saved search by its ID
+func (c *SQLClient) GetSavedSearchByID(id int) (*SavedSearch, error) {
+	search, err := scanSavedSearch(c.db.QueryRow("SELECT "+savedSearchColumns+" FROM saved_searches WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return nil, sql.ErrNoRows
+	} else if err != nil {
+		logError(fmt.Sprintf("Failed to query saved search with ID %d", id), err)
+		return nil, err
+	}
+	return &search, nil
+}
+
+// CreateSavedSearch creates a new saved search in the database
+func (c *SQLClient) CreateSavedSearch(search SavedSearch) (*SavedSearch, error) {
+	result, err := c.db.Exec(`
+		INSERT INTO saved_searches (name, query, enabled) VALUES (?, ?, ?)
+	`, search.Name, search.Query, search.Enabled)
+	if err != nil {
+		logError("Failed to create saved search", err)
+		return nil, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		logError("Failed to get last insert ID for saved search", err)
+		return nil, err
+	}
+
+	logSuccess(fmt.Sprintf("Created saved search with ID %d", id))
+	return c.GetSavedSearchByID(int(id))
+}
+
+// UpdateSavedSearch updates an existing saved search in the database. Its alerts
+// are kept; parts stored from now on are matched against the new query.
+func (c *SQLClient) UpdateSavedSearch(id int, search SavedSearch) (*SavedSearch, error) {
+	result, err := c.db.Exec(`
+		UPDATE saved_searches
+		SET name = ?, query = ?, enabled = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?
+	`, search.Name, search.Query, search.Enabled, id)
+	if err != nil {
+		logError(fmt.Sprintf("Failed to update saved search with ID %d", id), err)
+		return nil, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		logError("Failed to get rows affected", err)
+		return nil, err
+	}
+
+	if rowsAffected == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	logSuccess(fmt.Sprintf("Updated saved search with ID %d", id))
+	return c.GetSavedSearchByID(id)
+}
+
+// DeleteSavedSearch deletes a saved search and its alerts from the database
+func (c *SQLClient) DeleteSavedSearch(id int) error {
+	result, err := c.db.Exec("DELETE FROM saved_searches WHERE id = ?", id)
+	if err != nil {
+		logError(fmt.Sprintf("Failed to delete saved search with ID %d", id), err)
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		logError("Failed to get rows affected", err)
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	logSuccess(fmt.Sprintf("Deleted saved search with ID %d", id))
+	return nil
+}
+
+// CreateAlerts records an alert of a saved search for every listed part that matches
+// its filter, like GET /api/parts would return it. Parts that already have an alert
+// of the search are skipped. It returns the number of new alerts.
+func (c *SQLClient) CreateAlerts(savedSearchID int, filter PartsFilter, partIDs []int) (int64, error) {
+	if len(partIDs) == 0 {
+		return 0, nil
+	}
+
+	placeholders := make([]string, len(partIDs))
+	args := make([]interface{}, 0, len(partIDs)+1)
+	args = append(args, savedSearchID)
+	for i, partID := range partIDs {
+		placeholders[i] = "?"
+		args = append(args, partID)
+	}
+
+	filterQuery, filterParams := buildPartsFilter(filter)
+	query := fmt.Sprintf(`
+		INSERT OR IGNORE INTO alerts (saved_search_id, part_id)
+		SELECT ?, id FROM parts
+		WHERE id IN (%s)`, strings.Join(placeholders, ",")) + filterQuery
+	args = append(args, filterParams...)
+
+	result, err := c.db.Exec(query, args...)
+	if err != nil {
+		logError(fmt.Sprintf("Failed to create alerts of saved search %d", savedSearchID), err)
+		return 0, err
+	}
+
+	created, err := result.RowsAffected()
+	if err != nil {
+		logError("Failed to get rows affected", err)
+		return 0, err
+	}
+	return created, nil
+}
+
+// buildAlertsFilter returns the conditions for an AlertsFilter, to be appended after "WHERE 1=1"
+func buildAlertsFilter(filter AlertsFilter) (string, []interface{}) {
+	queryBuilder := strings.Builder{}
+	params := make([]interface{}, 0)
+
+	if filter.SavedSearchID != 0 {
+		queryBuilder.WriteString(" AND alerts.saved_search_id = ?")
+		params = append(params, filter.SavedSearchID)
+	}
+
+	if filter.UnreadOnly {
+		queryBuilder.WriteString(" AND alerts.read_at IS NULL")
+	}
+
+	if !filter.CreatedSince.IsZero() {
+		queryBuilder.WriteString(" AND alerts.created_at >= ?")
+		params = append(params, filter.CreatedSince.UTC().Format("2006-01-02 15:04:05"))
+	}
+
+	return queryBuilder.String(), params
+}
+
+// GetAlerts retrieves alerts with their parts, newest first
+func (c *SQLClient) GetAlerts(limit, offset int, filter AlertsFilter) ([]Alert, error) {
+	filterQuery, params := buildAlertsFilter(filter)
+	params = append(params, limit, offset)
+
+	rows, err := c.db.Query(`
+		SELECT alerts.id, alerts.saved_search_id, saved_searches.name, alerts.part_id, alerts.read_at, alerts.created_at
+		FROM alerts
+		JOIN saved_searches ON saved_searches.id = alerts.saved_search_id
+		WHERE 1=1`+filterQuery+`
+		ORDER BY alerts.created_at DESC, alerts.id DESC
+		LIMIT ? OFFSET ?
+	`, params...)
+	if err != nil {
+		logError("Failed to query alerts", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	alerts := make([]Alert, 0)
+	partIDs := make([]interface{}, 0)
+	for rows.Next() {
+		var alert Alert
+		err := rows.Scan(&alert.ID, &alert.SavedSearchID, &alert.SavedSearchName, &alert.PartID, &alert.ReadAt, &alert.CreatedAt)
+		if err != nil {
+			logError("Failed to scan alert data", err)
+			return nil, err
+		}
+		alert.Read = alert.ReadAt != nil
+		alerts = append(alerts, alert)
+		partIDs = append(partIDs, alert.PartID)
+	}
+
+	if err = rows.Err(); err != nil {
+		logError("Error iterating alerts", err)
+		return nil, err
+	}
+
+	if len(partIDs) == 0 {
+		return alerts, nil
+	}
+
+	// Load the parts of the page in one query
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(partIDs)), ",")
+	partRows, err := c.db.Query("SELECT "+partColumns+" FROM parts WHERE id IN ("+placeholders+")", partIDs...)
+	if err != nil {
+		logError("Failed to query alert parts", err)
+		return nil, err
+	}
+	defer partRows.Close()
+
+	parts, err := scanParts(partRows)
+	if err != nil {
+		return nil, err
+	}
+	partsByID := make(map[int]*Part, len(parts))
+	for i := range parts {
+		partsByID[parts[i].ID] = &parts[i]
+	}
+	for i := range alerts {
+		alerts[i].Part = partsByID[alerts[i].PartID]
+	}
+
+	return alerts, nil
+}
+
+// GetAlertsCount returns the number of alerts matching the filter
+func (c *SQLClient) GetAlertsCount(filter AlertsFilter) (int, error) {
+	filterQuery, params := buildAlertsFilter(filter)
+
+	var count int
+	err := c.db.QueryRow("SELECT COUNT(*) FROM alerts WHERE 1=1"+filterQuery, params...).Scan(&count)
+	if err != nil {
+		logError("Failed to count alerts", err)
+		return 0, err
+	}
+	return count, nil
+}
+
+// SetAlertRead marks an alert as read or unread
+func (c *SQLClient) SetAlertRead(id int, read bool) error {
+	query := `UPDATE alerts SET read_at = COALESCE(read_at, CURRENT_TIMESTAMP) WHERE id = ?`
+	if !read {
+		query = `UPDATE alerts SET read_at = NULL WHERE id = ?`
+	}
+
+	result, err := c.db.Exec(query, id)
+	if err != nil {
+		logError(fmt.Sprintf("Failed to update alert with ID %d", id), err)
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		logError("Failed to get rows affected", err)
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// MarkAlertsRead marks all unread alerts matching the filter as read and returns
+// how many there were
+func (c *SQLClient) MarkAlertsRead(filter AlertsFilter) (int64, error) {
+	filterQuery, params := buildAlertsFilter(filter)
+
+	result, err := c.db.Exec("UPDATE alerts SET read_at = CURRENT_TIMESTAMP WHERE read_at IS NULL"+filterQuery, params...)
+	if err != nil {
+		logError("Failed to mark alerts read", err)
+		return 0, err
+	}
+
+	marked, err := result.RowsAffected()
+	if err != nil {
+		logError("Failed to get rows affected", err)
+		return 0, err
+	}
+
+	logSuccess(fmt.Sprintf("Marked %d alerts read", marked))
+	return marked, nil
+}
+
+// DisplayCurrency returns the currency prices are converted to for filtering and sorting
+func (c *SQLClient) DisplayCurrency() string {
+	return c.displayCurrency
+}
+
+// SetDisplayCurrency changes the currency prices are converted to and converts the
+// prices of all stored parts
+func (c *SQLClient) SetDisplayCurrency(currency string) error {
+	c.displayCurrency = currency
+	result, err := c.db.Exec(`UPDATE parts SET price_display_amount = `+displayAmountSQL, currency)
+	if err != nil {
+		logError(fmt.Sprintf("Failed to convert prices to %s", currency), err)
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		logError("Failed to get rows affected", err)
+		return err
+	}
+
+	logSuccess(fmt.Sprintf("Converted the prices of %d parts to %s", rowsAffected, currency))
+	return nil
+}
+
+// GetExchangeRates retrieves all exchange rates
+func (c *SQLClient) GetExchangeRates() ([]ExchangeRate, error) {
+	rows, err := c.db.Query("SELECT currency, rate, updated_at FROM exchange_rates ORDER BY currency")
+	if err != nil {
+		logError("Failed to query exchange rates", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	rates := make([]ExchangeRate, 0)
+	for rows.Next() {
+		var rate ExchangeRate
+		if err := rows.Scan(&rate.Currency, &rate.Rate, &rate.UpdatedAt); err != nil {
+			logError("Failed to scan exchange rate", err)
+			return nil, err
+		}
+		rates = append(rates, rate)
+	}
+
+	if err = rows.Err(); err != nil {
+		logError("Error iterating exchange rates", err)
+		return nil, err
+	}
+	return rates, nil
+}
+
+// ReplaceExchangeRates replaces all exchange rates and converts the prices of all
+// stored parts with the new rates. All rates must share the same base currency.
+func (c *SQLClient) ReplaceExchangeRates(rates map[string]float64) error {
+	tx, err := c.db.Begin()
+	if err != nil {
+		logError("Failed to begin transaction", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM exchange_rates"); err != nil {
+		logError("Failed to delete exchange rates", err)
+		return err
+	}
+	for currency, rate := range rates {
+		if _, err := tx.Exec("INSERT INTO exchange_rates (currency, rate) VALUES (?, ?)", currency, rate); err != nil {
+			logError(fmt.Sprintf("Failed to insert exchange rate for %s", currency), err)
+			return err
+		}
+	}
+	if _, err := tx.Exec(`UPDATE parts SET price_display_amount = `+displayAmountSQL, c.displayCurrency); err != nil {
+		logError("Failed to convert prices", err)
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		logError("Failed to commit exchange rates", err)
+		return err
+	}
+
+	if _, ok := rates[c.displayCurrency]; !ok {
+		log.Printf("WARNING: No exchange rate for display currency %s, prices cannot be filtered or sorted", c.displayCurrency)
+	}
+	logSuccess(fmt.Sprintf("Replaced exchange rates with %d currencies", len(rates)))
+	return nil
+}
+
+// fetchRunColumns is the column list shared by every query that returns fetch runs
+const fetchRunColumns = `fetch_runs.id, fetch_runs.site_id, COALESCE(sites.site_name, ''), fetch_runs.job_id,
+	fetch_runs.trigger, fetch_runs.status, fetch_runs.started_at, fetch_runs.finished_at,
+	fetch_runs.parts_fetched, fetch_runs.parts_inserted, fetch_runs.parts_updated, fetch_runs.parts_deleted,
+	fetch_runs.parts_failed, fetch_runs.image_failures, fetch_runs.error`
+
+// scanFetchRun scans a row selected with fetchRunColumns into a FetchRun
+func scanFetchRun(scanner rowScanner) (FetchRun, error) {
+	var run FetchRun
+	err := scanner.Scan(
+		&run.ID, &run.SiteID, &run.SiteName, &run.JobID,
+		&run.Trigger, &run.Status, &run.StartedAt, &run.FinishedAt,
+		&run.PartsFetched, &run.PartsInserted, &run.PartsUpdated, &run.PartsDeleted,
+		&run.PartsFailed, &run.ImageFailures, &run.Error,
+	)
+	return run, err
+}
+
+// CreateFetchRun records the start of the fetch of a site in a job and returns its ID
+func (c *SQLClient) CreateFetchRun(siteID, jobID int, trigger string) (int, error) {
+	result, err := c.db.Exec(`INSERT INTO fetch_runs (site_id, job_id, trigger) VALUES (?, ?, ?)`, siteID, jobID, trigger)
+	if err != nil {
+		logError(fmt.Sprintf("Failed to create fetch run for site %d", siteID), err)
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		logError("Failed to get last insert ID", err)
+		return 0, err
+	}
+	return int(id), nil
+}
+
+// FinishFetchRun stores the outcome of a fetch run and marks it finished now
+func (c *SQLClient) FinishFetchRun(run FetchRun) error {
+	_, err := c.db.Exec(`UPDATE fetch_runs
+		SET status = ?, finished_at = CURRENT_TIMESTAMP, parts_fetched = ?, parts_inserted = ?, parts_updated = ?,
+			parts_deleted = ?, parts_failed = ?, error = ?
+		WHERE id = ?`,
+		run.Status, run.PartsFetched, run.PartsInserted, run.PartsUpdated,
+		run.PartsDeleted, run.PartsFailed, run.Error, run.ID)
+	if err != nil {
+		logError(fmt.Sprintf("Failed to finish fetch run %d", run.ID), err)
+		return err
+	}
+	return nil
+}
+
+// RecordFetchRunImageFailure counts a failed image download for the fetch run that queued it
+func (c *SQLClient) RecordFetchRunImageFailure(id int) error {
+	_, err := c.db.Exec(`UPDATE fetch_runs SET image_failures = image_failures + 1 WHERE id = ?`, id)
+	if err != nil {
+		logError(fmt.Sprintf("Failed to record image failure for fetch run %d", id), err)
+		return err
+	}
+	return nil
+}
+
+// FailInterruptedFetchRuns marks the fetch runs that were still running when the
+// previous process stopped as failed
+func (c *SQLClient) FailInterruptedFetchRuns() (int64, error) {
+	result, err := c.db.Exec(`UPDATE fetch_runs
+		SET status = 'failed', finished_at = CURRENT_TIMESTAMP, error = 'interrupted by a restart'
+		WHERE status = 'running'`)
+	if err != nil {
+		logError("Failed to fail interrupted fetch runs", err)
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// buildFetchRunsFilter creates the WHERE conditions for fetch runs based on the filter
+func buildFetchRunsFilter(filter FetchRunsFilter) (string, []interface{}) {
+	queryBuilder := strings.Builder{}
+	params := make([]interface{}, 0)
+
+	if filter.SiteID != 0 {
+		queryBuilder.WriteString(" AND fetch_runs.site_id = ?")
+		params = append(params, filter.SiteID)
+	}
+
+	if filter.Trigger != "" {
+		queryBuilder.WriteString(" AND fetch_runs.trigger = ?")
+		params = append(params, filter.Trigger)
+	}
+
+	if filter.Status != "" {
+		queryBuilder.WriteString(" AND fetch_runs.status = ?")
+		params = append(params, filter.Status)
+	}
+
+	if !filter.Since.IsZero() {
+		queryBuilder.WriteString(" AND fetch_runs.started_at >= ?")
+		params = append(params, filter.Since.UTC().Format("2006-01-02 15:04:05"))
+	}
+
+	if !filter.Until.IsZero() {
+		queryBuilder.WriteString(" AND fetch_runs.started_at < ?")
+		params = append(params, filter.Until.UTC().Format("2006-01-02 15:04:05"))
+	}
+
+	if filter.MaxFetched != nil {
+		queryBuilder.WriteString(" AND fetch_runs.parts_fetched <= ?")
+		params = append(params, *filter.MaxFetched)
+	}
+
+	return queryBuilder.String(), params
+}
+
+// GetFetchRuns retrieves fetch runs, newest first
+func (c *SQLClient) GetFetchRuns(limit, offset int, filter FetchRunsFilter) ([]FetchRun, error) {
+	filterQuery, params := buildFetchRunsFilter(filter)
+	params = append(params, limit, offset)
+
+	rows, err := c.db.Query(`
+		SELECT `+fetchRunColumns+`
+		FROM fetch_runs
+		LEFT JOIN sites ON sites.id = fetch_runs.site_id
+		WHERE 1=1`+filterQuery+`
+		ORDER BY fetch_runs.started_at DESC, fetch_runs.id DESC
+		LIMIT ? OFFSET ?
+	`, params...)
+	if err != nil {
+		logError("Failed to query fetch runs", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	runs := make([]FetchRun, 0)
+	for rows.Next() {
+		run, err := scanFetchRun(rows)
+		if err != nil {
+			logError("Failed to scan fetch run data", err)
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+
+	if err = rows.Err(); err != nil {
+		logError("Error iterating fetch runs", err)
+		return nil, err
+	}
+	return runs, nil
+}
+
+// GetLastFetchRun returns the newest fetch run of a site, or sql.ErrNoRows when it
+// was never fetched
+func (c *SQLClient) GetLastFetchRun(siteID int) (*FetchRun, error) {
+	run, err := scanFetchRun(c.db.QueryRow(`
+		SELECT `+fetchRunColumns+`
+		FROM fetch_runs
+		LEFT JOIN sites ON sites.id = fetch_runs.site_id
+		WHERE fetch_runs.site_id = ?
+		ORDER BY fetch_runs.started_at DESC, fetch_runs.id DESC
+		LIMIT 1
+	`, siteID))
+	if err == sql.ErrNoRows {
+		return nil, sql.ErrNoRows
+	} else if err != nil {
+		logError(fmt.Sprintf("Failed to query last fetch run of site %d", siteID), err)
+		return nil, err
+	}
+	return &run, nil
+}
+
+// GetFetchRunsCount returns the number of fetch runs matching the filter
+func (c *SQLClient) GetFetchRunsCount(filter FetchRunsFilter) (int, error) {
+	filterQuery, params := buildFetchRunsFilter(filter)
+
+	var count int
+	err := c.db.QueryRow("SELECT COUNT(*) FROM fetch_runs WHERE 1=1"+filterQuery, params...).Scan(&count)
+	if err != nil {
+		logError("Failed to count fetch runs", err)
+		return 0, err
+	}
+	return count, nil
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+
+	"github.com/pressly/goose/v3"
+)
+
+// newTypeMigration returns the Go migration that classifies the parts stored before
+// parts were categorised
+func newTypeMigration() *goose.Migration {
+	return goose.NewGoMigration(20251031090100,
+		&goose.GoFunc{RunTx: migrateTypes},
+		nil,
+	)
+}
+
+// migrateTypes sets the type_name of every part from its name and description
+func migrateTypes(ctx context.Context, tx *sql.Tx) error {
+	changed, err := reclassifyParts(ctx, tx)
+	if err != nil {
+		return fmt.Errorf("failed to classify parts: %w", err)
+	}
+
+	log.Printf("[TypeMigration] Classified %d parts", changed)
+	return nil
+}